@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/harvey-earth/pocdeploy/internal"
+)
+
+var backupWait bool
+
+// backupCmd represents the backup command
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "manage backups of the CloudNative PG cluster",
+	Long: `manages backups of the CloudNative PG cluster to the object store configured in backend.backup.
+
+Scheduled backups are created by the "create" command when backend.backup is set.`,
+	Example: `pocdeploy backup now
+pocdeploy backup list`,
+}
+
+// backupNowCmd represents the backup now command
+var backupNowCmd = &cobra.Command{
+	Use:     "now",
+	Short:   "start a backup",
+	Long:    `starts an on-demand backup of the CloudNative PG cluster and prints its name.`,
+	Example: `pocdeploy backup now --wait`,
+	Run: func(cmd *cobra.Command, args []string) {
+		name, err := internal.CreateBackup(backupWait)
+		if err != nil {
+			err = fmt.Errorf("Error creating backup: %w", err)
+			internal.Error(err)
+		}
+
+		fmt.Println(name)
+	},
+}
+
+// backupListCmd represents the backup list command
+var backupListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "list backups",
+	Long:    `lists the backups of the CloudNative PG clusters, including clusters replaced by a restore, oldest first.`,
+	Example: `pocdeploy backup list`,
+	Run: func(cmd *cobra.Command, args []string) {
+		backups, err := internal.ListBackups()
+		if err != nil {
+			err = fmt.Errorf("Error listing backups: %w", err)
+			internal.Error(err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tCLUSTER\tPHASE\tSTARTED\tSTOPPED\tERROR")
+		for _, b := range backups {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", b.Name, b.Cluster, b.Phase, b.Started, b.Stopped, b.Error)
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	backupNowCmd.Flags().BoolVarP(&backupWait, "wait", "w", false, "wait for the backup to complete")
+	backupCmd.AddCommand(backupNowCmd)
+	backupCmd.AddCommand(backupListCmd)
+	rootCmd.AddCommand(backupCmd)
+}
@@ -18,8 +18,34 @@ var createCmd = &cobra.Command{
 If the type is not set, the default is a local Kind cluster.`,
 	Example: `pocdeploy create -t [kind]`,
 	Run: func(cmd *cobra.Command, args []string) {
-		frontendType := viper.GetString("frontend.type")
 		clusterType := viper.GetString("type")
+		// Look up the frontend framework before creating anything
+		fw, err := internal.GetFramework(viper.GetString("frontend.type"))
+		if err != nil {
+			err = fmt.Errorf("Error with frontend type: %w", err)
+			internal.Error(err)
+		}
+		if err = internal.CheckBuildConfig(fw); err != nil {
+			err = fmt.Errorf("Error with build config: %w", err)
+			internal.Error(err)
+		}
+		if err = internal.CheckBackendConfig(); err != nil {
+			err = fmt.Errorf("Error with backend config: %w", err)
+			internal.Error(err)
+		}
+		if err = internal.CheckWorkersConfig(); err != nil {
+			err = fmt.Errorf("Error with workers config: %w", err)
+			internal.Error(err)
+		}
+		if err = internal.CheckCronConfig(); err != nil {
+			err = fmt.Errorf("Error with cron config: %w", err)
+			internal.Error(err)
+		}
+		if err = internal.CheckServicesConfig(); err != nil {
+			err = fmt.Errorf("Error with services config: %w", err)
+			internal.Error(err)
+		}
+
 		// Create cluster
 		if clusterType == "kind" {
 			err := internal.CreateKindCluster(viper.GetString("name"))
@@ -42,7 +68,7 @@ If the type is not set, the default is a local Kind cluster.`,
 		}
 
 		// Build image using name and version from config and return them
-		imgName, imgVers, err := internal.BuildImage()
+		imgName, imgVers, err := internal.BuildImage(fw)
 		if err != nil {
 			err = fmt.Errorf("Error building image: %w", err)
 			internal.Error(err)
@@ -68,7 +94,13 @@ If the type is not set, the default is a local Kind cluster.`,
 			internal.Error(err)
 		}
 
-		if err = internal.ConfigureFrontend(); err != nil {
+		// Deploy Redis and RabbitMQ before the frontend reads their credentials
+		if err = internal.ConfigureServices(); err != nil {
+			err = fmt.Errorf("Error installing services: %w", err)
+			internal.Error(err)
+		}
+
+		if err = internal.ConfigureFrontend(fw); err != nil {
 			err = fmt.Errorf("Error installing frontend: %w", err)
 			internal.Error(err)
 		}
@@ -79,24 +111,28 @@ If the type is not set, the default is a local Kind cluster.`,
 			internal.Error(err)
 		}
 
-		// Run Django migrations
-		if err = internal.InitBackend(frontendType); err != nil {
+		// Run framework migrations
+		if err = internal.InitBackend(fw); err != nil {
 			err = fmt.Errorf("Error running migrations to init backend: %w", err)
 			internal.Error(err)
 		}
 
+		// Load seed data once migrations complete
+		if err = internal.SeedBackend(fw); err != nil {
+			err = fmt.Errorf("Error seeding backend: %w", err)
+			internal.Error(err)
+		}
+
 		// Deploy prometheus
 		if err = internal.ConfigureMonitoring(); err != nil {
 			err = fmt.Errorf("Error installing monitoring: %w", err)
 			internal.Error(err)
 		}
 
-		if frontendType == "django" {
-			// Create job that creates superuser for Django
-			if err = internal.CreateDjangoAdminUser(); err != nil {
-				err = fmt.Errorf("Error creating superuser: %w", err)
-				internal.Error(err)
-			}
+		// Create job that creates the admin user if the framework supports it
+		if err = internal.CreateAdminUser(fw); err != nil {
+			err = fmt.Errorf("Error creating superuser: %w", err)
+			internal.Error(err)
 		}
 	},
 }
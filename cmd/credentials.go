@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/harvey-earth/pocdeploy/internal"
+)
+
+// credentialsCmd represents the credentials command
+var credentialsCmd = &cobra.Command{
+	Use:   "credentials",
+	Short: "print the admin login and database connection string",
+	Long: `prints the admin login and database connection string stored in the cluster secrets.
+
+Generated admin passwords are only printed once by the "create" command, use this command to retrieve them later.
+The database host is only resolvable from inside the cluster.`,
+	Example: `pocdeploy credentials`,
+	Run: func(cmd *cobra.Command, args []string) {
+		creds, err := internal.GetCredentials()
+		if err != nil {
+			err = fmt.Errorf("Error reading credentials: %w", err)
+			internal.Error(err)
+		}
+
+		if creds.AdminUsername != "" {
+			fmt.Println("Admin username:", creds.AdminUsername)
+			fmt.Println("Admin email:   ", creds.AdminEmail)
+			fmt.Println("Admin password:", creds.AdminPassword)
+		}
+		fmt.Println("Database URL:  ", creds.DatabaseURL)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(credentialsCmd)
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/harvey-earth/pocdeploy/internal"
+)
+
+var dbSuperuser bool
+var dbDumpFormat string
+var dbForwardPort int
+
+// dbCmd represents the db command
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "access the CloudNative PG database",
+	Long: `accesses the application database of the active CloudNative PG cluster.
+
+Commands run on the primary instance, so no credentials or local Postgres client are needed.`,
+	Example: `pocdeploy db shell
+pocdeploy db dump > dump.sql
+pocdeploy db forward --port 15432
+pocdeploy db info`,
+}
+
+// dbShellCmd represents the db shell command
+var dbShellCmd = &cobra.Command{
+	Use:   "shell",
+	Short: "open a psql shell",
+	Long: `opens an interactive psql shell on the primary instance.
+
+The session uses the application database owner role, set --superuser to stay connected as postgres.`,
+	Example: `pocdeploy db shell`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := internal.DatabaseShell(dbSuperuser); err != nil {
+			err = fmt.Errorf("Error opening database shell: %w", err)
+			internal.Error(err)
+		}
+	},
+}
+
+// dbDumpCmd represents the db dump command
+var dbDumpCmd = &cobra.Command{
+	Use:   "dump",
+	Short: "dump the database to stdout",
+	Long: `streams a pg_dump of the application database to stdout, without owners or privileges.
+
+Use --format custom for a dump that can be loaded with pg_restore or backend.seed.file.`,
+	Example: `pocdeploy db dump > dump.sql
+pocdeploy db dump --format custom > demo.dump`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := internal.DatabaseDump(os.Stdout, dbDumpFormat); err != nil {
+			err = fmt.Errorf("Error dumping database: %w", err)
+			internal.Error(err)
+		}
+	},
+}
+
+// dbForwardCmd represents the db forward command
+var dbForwardCmd = &cobra.Command{
+	Use:   "forward",
+	Short: "forward a local port to the database",
+	Long: `forwards a local port to the primary instance behind the -rw service and prints the connection URL.
+
+The forward runs until interrupted with Ctrl-C.`,
+	Example: `pocdeploy db forward --port 15432`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := internal.ForwardDatabase(dbForwardPort); err != nil {
+			err = fmt.Errorf("Error forwarding database port: %w", err)
+			internal.Error(err)
+		}
+	},
+}
+
+// dbInfoCmd represents the db info command
+var dbInfoCmd = &cobra.Command{
+	Use:     "info",
+	Short:   "print the cluster status and connection details",
+	Long:    `prints the status of the active CloudNative PG cluster along with its services and in-cluster connection URL.`,
+	Example: `pocdeploy db info`,
+	Run: func(cmd *cobra.Command, args []string) {
+		info, err := internal.GetDatabaseInfo()
+		if err != nil {
+			err = fmt.Errorf("Error reading database info: %w", err)
+			internal.Error(err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Cluster:\t%s\n", info.Cluster)
+		fmt.Fprintf(w, "Phase:\t%s\n", info.Phase)
+		fmt.Fprintf(w, "Instances:\t%d/%d ready\n", info.ReadyInstances, info.Instances)
+		fmt.Fprintf(w, "Primary:\t%s\n", info.Primary)
+		fmt.Fprintf(w, "Image:\t%s\n", info.Image)
+		fmt.Fprintf(w, "Database:\t%s\n", info.Database)
+		fmt.Fprintf(w, "User:\t%s\n", info.User)
+		fmt.Fprintf(w, "Read-write service:\t%s\n", info.ReadWrite)
+		fmt.Fprintf(w, "Read-only service:\t%s\n", info.ReadOnly)
+		fmt.Fprintf(w, "Database URL:\t%s\n", info.URL)
+		w.Flush()
+	},
+}
+
+func init() {
+	dbShellCmd.Flags().BoolVar(&dbSuperuser, "superuser", false, "connect as the postgres superuser")
+	dbDumpCmd.Flags().StringVar(&dbDumpFormat, "format", "plain", "pg_dump format (plain, custom, tar)")
+	dbForwardCmd.Flags().IntVarP(&dbForwardPort, "port", "p", 5432, "local port to listen on")
+	dbCmd.AddCommand(dbShellCmd)
+	dbCmd.AddCommand(dbDumpCmd)
+	dbCmd.AddCommand(dbForwardCmd)
+	dbCmd.AddCommand(dbInfoCmd)
+	rootCmd.AddCommand(dbCmd)
+}
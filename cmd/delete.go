@@ -9,15 +9,28 @@ import (
 	"github.com/harvey-earth/pocdeploy/internal"
 )
 
+var deleteKeepCluster bool
+
 // destroyCmd represents the destroy command
 var deleteCmd = &cobra.Command{
 	Use:   "delete",
 	Short: "deletes kubernetes cluster",
 	Long: `deletes the kubernetes cluster created with the "create" command.
 
-This will delete resources using Terraform, and it will not be graceful.`,
-	Example: `pocdeploy delete -t [kind]`,
+This will delete resources using Terraform, and it will not be graceful.
+
+With --keep-cluster only the app namespace is deleted, removing the frontend, the backend cluster and the services
+along with their volumes, while the Kubernetes cluster and the operators are kept.`,
+	Example: `pocdeploy delete -t [kind]
+pocdeploy delete --keep-cluster`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if deleteKeepCluster {
+			if err := internal.DeleteAppNamespace(); err != nil {
+				err = fmt.Errorf("Error deleting app namespace: %w", err)
+				internal.Error(err)
+			}
+			return
+		}
 
 		// Run DeleteKindCluster for type kind
 		if viper.GetString("type") == "kind" {
@@ -30,5 +43,6 @@ This will delete resources using Terraform, and it will not be graceful.`,
 }
 
 func init() {
+	deleteCmd.Flags().BoolVar(&deleteKeepCluster, "keep-cluster", false, "delete the app namespace but keep the kubernetes cluster")
 	rootCmd.AddCommand(deleteCmd)
 }
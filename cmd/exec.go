@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/harvey-earth/pocdeploy/internal"
+)
+
+var execNoTTY bool
+
+// execCmd represents the exec command
+var execCmd = &cobra.Command{
+	Use:   "exec -- <command> [args...]",
+	Short: "run a command in a frontend pod",
+	Long: `runs a command in the frontend container of a running frontend pod and exits with its exit code.
+
+A terminal is allocated when stdin is a terminal, so interactive commands such as consoles work. Set --no-tty to
+pipe input and output instead.`,
+	Example: `pocdeploy exec -- bin/rails console
+pocdeploy exec -- python manage.py shell
+pocdeploy exec -T -- env`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		code, err := internal.ExecFrontend(args, !execNoTTY)
+		if err != nil {
+			err = fmt.Errorf("Error running command: %w", err)
+			internal.Error(err)
+		}
+
+		os.Exit(code)
+	},
+}
+
+func init() {
+	execCmd.Flags().BoolVarP(&execNoTTY, "no-tty", "T", false, "don't allocate a terminal")
+	rootCmd.AddCommand(execCmd)
+}
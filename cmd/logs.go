@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/harvey-earth/pocdeploy/internal"
+)
+
+var logsComponents []string
+var logsFollow bool
+var logsSince time.Duration
+
+// logsCmd represents the logs command
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "show logs of the deployment",
+	Long: `shows the logs of every init container and container in the pods of the selected components, each line prefixed
+with the pod and container name.
+
+Components are ` + strings.Join(internal.LogComponents, ", ") + `. Several can be selected by
+repeating --component or separating them with commas. With --follow new pods and restarted containers are picked up until
+interrupted.`,
+	Example: `pocdeploy logs
+pocdeploy logs --component backend,cnpg-operator --since 10m
+pocdeploy logs -c frontend -f`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := internal.Logs(logsComponents, logsFollow, logsSince, os.Stdout); err != nil {
+			err = fmt.Errorf("Error reading logs: %w", err)
+			internal.Error(err)
+		}
+	},
+}
+
+func init() {
+	logsCmd.Flags().StringSliceVarP(&logsComponents, "component", "c", []string{"frontend"}, "components to show logs of")
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "stream new logs until interrupted")
+	logsCmd.Flags().DurationVar(&logsSince, "since", 0, "only show logs newer than a duration, ex. 10m")
+	rootCmd.AddCommand(logsCmd)
+}
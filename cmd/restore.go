@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/harvey-earth/pocdeploy/internal"
+)
+
+var restoreFrom string
+var restoreDeleteOld bool
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "restore the CloudNative PG cluster from a backup",
+	Long: `creates a new CloudNative PG cluster recovered from the object store configured in backend.backup and switches the frontend to it.
+
+--from takes the name of a backup from "pocdeploy backup list", an RFC 3339 timestamp to recover to, or latest to replay all archived WAL.
+The old cluster is kept unless --delete-old is set, so the restore can be repeated from it.`,
+	Example: `pocdeploy restore --from latest
+pocdeploy restore --from 2024-10-01T12:00:00Z --delete-old`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := internal.RestoreBackend(restoreFrom, restoreDeleteOld); err != nil {
+			err = fmt.Errorf("Error restoring backend: %w", err)
+			internal.Error(err)
+		}
+	},
+}
+
+func init() {
+	restoreCmd.Flags().StringVar(&restoreFrom, "from", "", "backup name, RFC 3339 timestamp or latest")
+	restoreCmd.MarkFlagRequired("from")
+	restoreCmd.Flags().BoolVar(&restoreDeleteOld, "delete-old", false, "delete the replaced cluster after the restore")
+	rootCmd.AddCommand(restoreCmd)
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -11,6 +12,7 @@ import (
 )
 
 var cfgFile string
+var secretsFile string
 
 // Root returns the root command to create manpages
 func Root() *cobra.Command {
@@ -40,6 +42,8 @@ func init() {
 
 	// Config File
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/pocdeploy.yaml)")
+	// SOPS encrypted secrets file
+	rootCmd.PersistentFlags().StringVar(&secretsFile, "secrets", "", "SOPS encrypted secrets file merged into the config (default is pocdeploy.secrets.yaml next to the config file)")
 	// Cluster Type
 	rootCmd.PersistentFlags().StringP("type", "t", "kind", "Type of cluster(kind)")
 	viper.BindPFlag("type", rootCmd.PersistentFlags().Lookup("type"))
@@ -71,15 +75,29 @@ func initConfig() {
 		viper.SetConfigName("pocdeploy")
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	// Read in environment variables that match, nested keys use underscores (ex. POCDEPLOY_FRONTEND_ADMIN_PASSWORD)
+	viper.SetEnvPrefix("pocdeploy")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
 		if q := viper.GetBool("quiet"); !q {
-		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+			fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 		}
 	}
 
+	// Merge in decrypted secrets and resolve ${env:NAME} and ${file:path} values
+	cobra.CheckErr(internal.MergeSecretsFile(secretsFile))
+	deprecated, err := internal.BindDeprecatedEnv()
+	cobra.CheckErr(err)
+	if q := viper.GetBool("quiet"); !q {
+		for _, name := range deprecated {
+			fmt.Fprintf(os.Stderr, "Warning: environment variable %s is deprecated, use POCDEPLOY_%s\n", name, name)
+		}
+	}
+	cobra.CheckErr(internal.ResolveSecretReferences())
+
 	// Initialize Logger
 	if err := internal.InitLogger(); err != nil {
 		panic(err)
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/harvey-earth/pocdeploy/internal"
+)
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run -- <command> [args...]",
+	Short: "run a command in a new pod",
+	Long: `runs a command in a throwaway job from the current frontend image, with the same environment and config files
+as the frontend, streams its output and exits with its exit code.
+
+The job runs once without retries and is removed 10 minutes after it finishes. Use "exec" for interactive commands.`,
+	Example: `pocdeploy run -- bin/rails db:migrate:status
+pocdeploy run -- python manage.py check --deploy`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		fw, err := internal.GetFramework(viper.GetString("frontend.type"))
+		if err != nil {
+			err = fmt.Errorf("Error with frontend type: %w", err)
+			internal.Error(err)
+		}
+
+		code, err := internal.RunCommand(fw, args, os.Stdout)
+		if err != nil {
+			err = fmt.Errorf("Error running command: %w", err)
+			internal.Error(err)
+		}
+
+		os.Exit(code)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/harvey-earth/pocdeploy/internal"
+)
+
+// runJobCmd represents the run-job command
+var runJobCmd = &cobra.Command{
+	Use:   "run-job <name>",
+	Short: "run a scheduled job now",
+	Long: `starts a job from the CronJob of the frontend.cron entry with the given name and streams its output.
+
+The job runs once without retries, and the command fails if the job fails.`,
+	Example: `pocdeploy run-job cleanup`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := internal.RunCronJob(args[0], os.Stdout); err != nil {
+			err = fmt.Errorf("Error running job: %w", err)
+			internal.Error(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(runJobCmd)
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/harvey-earth/pocdeploy/internal"
+)
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "show the state of the deployment",
+	Long: `shows the state of the frontend, the workers, the backend cluster and the services created with the "create" command.
+
+Redis, RabbitMQ and MinIO are only listed when they are enabled in the config.`,
+	Example: `pocdeploy status`,
+	Run: func(cmd *cobra.Command, args []string) {
+		statuses, err := internal.GetStatus()
+		if err != nil {
+			err = fmt.Errorf("Error getting status: %w", err)
+			internal.Error(err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "COMPONENT\tNAME\tREADY\tSTATUS")
+		for _, s := range statuses {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Component, s.Name, s.Ready, s.Status)
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/harvey-earth/pocdeploy/internal"
+)
+
+// updateCmd represents the update command
+var updateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "apply config changes to a running deployment",
+	Long: `applies changes in the backend config section to the CloudNative PG cluster created with the "create" command,
+changes in the services config section to Redis and RabbitMQ, and changes in the frontend env, config files, workers
+and cron, rolling the frontend pods when they change.
+
+The cluster is updated in place, the bootstrap database, owner and storage classes can't be changed.`,
+	Example: `pocdeploy update`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Look up the frontend framework before updating anything
+		fw, err := internal.GetFramework(viper.GetString("frontend.type"))
+		if err != nil {
+			err = fmt.Errorf("Error with frontend type: %w", err)
+			internal.Error(err)
+		}
+		if err := internal.UpdateBackend(); err != nil {
+			err = fmt.Errorf("Error updating backend: %w", err)
+			internal.Error(err)
+		}
+		if err := internal.ConfigureServices(); err != nil {
+			err = fmt.Errorf("Error updating services: %w", err)
+			internal.Error(err)
+		}
+		if err := internal.UpdateFrontend(fw); err != nil {
+			err = fmt.Errorf("Error updating frontend: %w", err)
+			internal.Error(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(updateCmd)
+}
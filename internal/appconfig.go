@@ -0,0 +1,182 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/harvey-earth/pocdeploy/internal/models"
+)
+
+// ConfigHashAnnotation is set on frontend pod templates so config changes trigger a rollout
+const ConfigHashAnnotation = "pocdeploy/config-hash"
+
+// appConfig holds the user defined environment and config files applied to every frontend workload
+type appConfig struct {
+	env          []corev1.EnvVar
+	envFrom      []corev1.EnvFromSource
+	files        map[string]string
+	volumes      []corev1.Volume
+	volumeMounts []corev1.VolumeMount
+	hash         string
+}
+
+// invalidConfigMapKey matches characters not allowed in configmap keys
+var invalidConfigMapKey = regexp.MustCompile(`[^-._a-zA-Z0-9]`)
+
+// loadAppConfig reads frontend.env, frontend.env_from_secret and frontend.config_files
+func loadAppConfig() (appConfig, error) {
+	cfg := appConfig{
+		files: map[string]string{},
+	}
+	hash := sha256.New()
+
+	// Literal values, sorted so the hash is stable
+	literals := viper.GetStringMapString("frontend.env")
+	names := make([]string, 0, len(literals))
+	for name := range literals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// Viper lowercases keys, environment variables are conventionally uppercase
+		envName := strings.ToUpper(name)
+		cfg.env = append(cfg.env, corev1.EnvVar{
+			Name:  envName,
+			Value: literals[name],
+		})
+		fmt.Fprintf(hash, "env:%s=%s\n", envName, literals[name])
+	}
+
+	// Values from existing secrets
+	var secretEnv []models.SecretEnv
+	if err := viper.UnmarshalKey("frontend.env_from_secret", &secretEnv); err != nil {
+		err = fmt.Errorf("error reading frontend.env_from_secret: %w", err)
+		return cfg, err
+	}
+	for _, s := range secretEnv {
+		if s.Secret == "" {
+			err := fmt.Errorf("frontend.env_from_secret entry %q is missing secret", s.Name)
+			return cfg, err
+		}
+		if s.Name == "" && s.Key == "" {
+			cfg.envFrom = append(cfg.envFrom, corev1.EnvFromSource{
+				SecretRef: &corev1.SecretEnvSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: s.Secret,
+					},
+				},
+			})
+		} else {
+			if s.Name == "" || s.Key == "" {
+				err := fmt.Errorf("frontend.env_from_secret entry for secret %s needs both name and key, or neither", s.Secret)
+				return cfg, err
+			}
+			cfg.env = append(cfg.env, corev1.EnvVar{
+				Name: s.Name,
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: s.Secret,
+						},
+						Key: s.Key,
+					},
+				},
+			})
+		}
+		fmt.Fprintf(hash, "secret:%s=%s/%s\n", s.Name, s.Secret, s.Key)
+	}
+
+	// Files mounted from the frontend-config-files configmap
+	var configFiles []models.ConfigFile
+	if err := viper.UnmarshalKey("frontend.config_files", &configFiles); err != nil {
+		err = fmt.Errorf("error reading frontend.config_files: %w", err)
+		return cfg, err
+	}
+	for i, f := range configFiles {
+		if f.Source == "" || f.Mount == "" {
+			err := fmt.Errorf("frontend.config_files entry %d needs both source and mount", i)
+			return cfg, err
+		}
+		// Relative sources are relative to the config file, like ${file:path} references
+		source := f.Source
+		if !filepath.IsAbs(source) {
+			source = filepath.Join(configDir(), source)
+		}
+		content, err := os.ReadFile(source)
+		if err != nil {
+			err = fmt.Errorf("error reading config file %s: %w", source, err)
+			return cfg, err
+		}
+		key := fmt.Sprintf("%d-%s", i, invalidConfigMapKey.ReplaceAllString(filepath.Base(f.Source), "_"))
+		cfg.files[key] = string(content)
+		cfg.volumeMounts = append(cfg.volumeMounts, corev1.VolumeMount{
+			Name:      "config-files",
+			MountPath: f.Mount,
+			SubPath:   key,
+			ReadOnly:  true,
+		})
+		fmt.Fprintf(hash, "file:%s=%s\n%s\n", key, f.Mount, content)
+	}
+	if len(cfg.files) > 0 {
+		cfg.volumes = []corev1.Volume{
+			{
+				Name: "config-files",
+				VolumeSource: corev1.VolumeSource{
+					ConfigMap: &corev1.ConfigMapVolumeSource{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: "frontend-config-files",
+						},
+					},
+				},
+			},
+		}
+	}
+
+	cfg.hash = hex.EncodeToString(hash.Sum(nil))
+	return cfg, nil
+}
+
+// configFilesConfigMap creates or updates the frontend-config-files configmap if there are config files
+func configFilesConfigMap(clientset *kubernetes.Clientset, cfg appConfig) error {
+	if len(cfg.files) == 0 {
+		return nil
+	}
+	Debug("Creating frontend config files configmap")
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "frontend-config-files",
+			Namespace: "app",
+			Labels: map[string]string{
+				"app.kubernetes.io/component": "configmap",
+				"app.kubernetes.io/name":      "frontend-config-files",
+			},
+		},
+		Data: cfg.files,
+	}
+
+	configMaps := clientset.CoreV1().ConfigMaps("app")
+	_, err := configMaps.Create(context.Background(), configMap, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		_, err = configMaps.Update(context.Background(), configMap, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+
+	Debug("Frontend config files configmap created")
+	return nil
+}
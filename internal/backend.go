@@ -1,36 +1,92 @@
 package internal
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"time"
 
-	"github.com/spf13/viper"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
 
 	d "github.com/harvey-earth/pocdeploy/deploy"
+	"github.com/harvey-earth/pocdeploy/internal/models"
 )
 
-// ConfigureBackend sets up CloudNative PG
-func ConfigureBackend() error {
+// postgresGVR is the CloudNative PG Cluster resource
+var postgresGVR = schema.GroupVersionResource{
+	Group:    "postgresql.cnpg.io",
+	Version:  "v1",
+	Resource: "clusters",
+}
+
+// cnpgBackend deploys Postgres with the CloudNative PG operator
+type cnpgBackend struct{}
+
+func init() {
+	registerBackend(cnpgBackend{})
+}
+
+func (cnpgBackend) Name() string {
+	return "cnpg"
+}
+
+func (cnpgBackend) Scheme() string {
+	return "postgresql"
+}
+
+// Validate accepts every option, as the backend config section was designed for CloudNative PG
+func (cnpgBackend) Validate(cfg models.Backend) error {
+	return nil
+}
+
+// Status reads the phase and ready instances of the active cluster
+func (cnpgBackend) Status() (models.ComponentStatus, error) {
+	status := models.ComponentStatus{
+		Component: "backend",
+		Name:      activeClusterName(),
+	}
+
+	clientdyn, err := kubernetesDynamicClient()
+	if err != nil {
+		return status, err
+	}
+	cluster, err := clientdyn.Resource(postgresGVR).Namespace(AppNamespace).Get(context.Background(), status.Name, metav1.GetOptions{})
+	if err != nil {
+		err = fmt.Errorf("error getting %s: %w", status.Name, err)
+		return status, err
+	}
+	instances, _, _ := unstructured.NestedInt64(cluster.Object, "spec", "instances")
+	ready, _, _ := unstructured.NestedInt64(cluster.Object, "status", "readyInstances")
+	status.Ready = fmt.Sprintf("%d/%d", ready, instances)
+	status.Status, _, _ = unstructured.NestedString(cluster.Object, "status", "phase")
+
+	return status, nil
+}
+
+// Configure sets up CloudNative PG
+func (cnpgBackend) Configure(cfg models.Backend) error {
 	Info("Configuring CloudNative PG Cluster")
 	namespace := "app"
 
-	clientset, err := kubernetesDynamicClient()
-	if err != nil {
+	// The cluster references the backup credentials secret
+	if err := configureBackupStore(cfg); err != nil {
 		return err
 	}
 
-	postgresGVR := schema.GroupVersionResource{
-		Group:    "postgresql.cnpg.io",
-		Version:  "v1",
-		Resource: "clusters",
+	clientset, err := kubernetesDynamicClient()
+	if err != nil {
+		return err
 	}
 
 	postgresCluster := &unstructured.Unstructured{
@@ -38,19 +94,14 @@ func ConfigureBackend() error {
 			"apiVersion": "postgresql.cnpg.io/v1",
 			"kind":       "Cluster",
 			"metadata": map[string]any{
-				"name":      "poc-backend-cluster",
+				"name":      ClusterName,
 				"namespace": namespace,
 				"labels": map[string]string{
 					"app.kubernetes.io/component": "cluster",
 					"app.kubernetes.io/name":      "backend",
 				},
 			},
-			"spec": map[string]any{
-				"instances": 3,
-				"storage": map[string]any{
-					"size": "1Gi",
-				},
-			},
+			"spec": clusterSpec(cfg),
 		},
 	}
 
@@ -68,37 +119,180 @@ func ConfigureBackend() error {
 		}
 	}
 
+	if err = configureScheduledBackup(cfg); err != nil {
+		err = fmt.Errorf("error configuring scheduled backup: %w", err)
+		return err
+	}
+
+	if err = configurePoolers(cfg, activeClusterName()); err != nil {
+		err = fmt.Errorf("error configuring poolers: %w", err)
+		return err
+	}
+
 	Info("CloudNative PG Cluster configured")
 	return nil
 }
 
-// InitBackend starts the job to initialize the backend for each type of framework
-func InitBackend(t string) error {
-	Info("Starting backend initialization")
-	switch t {
-	case "django":
-		err := initDjangoBackend()
-		if err != nil {
-			err = fmt.Errorf("error initializing django backend: %w", err)
+// Update applies the backend config to the running CloudNative PG Cluster. Bootstrap settings and storage
+// classes can't be changed once the cluster exists, so differences in them are reported instead.
+func (cnpgBackend) Update(cfg models.Backend) error {
+	Info("Updating CloudNative PG Cluster")
+	namespace := "app"
+
+	clientset, err := kubernetesDynamicClient()
+	if err != nil {
+		return err
+	}
+	clusters := clientset.Resource(postgresGVR).Namespace(namespace)
+
+	name := activeClusterName()
+	cluster, err := clusters.Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		err = fmt.Errorf("error getting %s: %w", name, err)
+		return err
+	}
+	spec, _, err := unstructured.NestedMap(cluster.Object, "spec")
+	if err != nil {
+		err = fmt.Errorf("error reading %s spec: %w", name, err)
+		return err
+	}
+
+	// Clusters created by restore bootstrap from recovery instead, which sets the same database and owner
+	bootstrap := "initdb"
+	if _, found, _ := unstructured.NestedMap(spec, "bootstrap", "initdb"); !found {
+		bootstrap = "recovery"
+	}
+
+	for _, field := range []struct {
+		path  []string
+		value string
+	}{
+		{[]string{"bootstrap", bootstrap, "database"}, cfg.Database},
+		{[]string{"bootstrap", bootstrap, "owner"}, cfg.Owner},
+		{[]string{"storage", "storageClass"}, cfg.Storage.StorageClass},
+		{[]string{"walStorage", "storageClass"}, cfg.WalStorage.StorageClass},
+	} {
+		current, _, _ := unstructured.NestedString(spec, field.path...)
+		if field.value != "" && current != field.value {
+			err = fmt.Errorf("%s can't be changed from %q to %q on an existing cluster", strings.Join(field.path, "."), current, field.value)
 			return err
 		}
-	case "ror":
-		err := initRORBackend()
-		if err != nil {
-			err = fmt.Errorf("error initializing ruby on rails backend: %w", err)
+	}
+	_, hasWal := spec["walStorage"]
+	if hasWal != (cfg.WalStorage.Size != "") {
+		err = fmt.Errorf("backend.wal_storage can't be added or removed on an existing cluster")
+		return err
+	}
+
+	// Fields not in the config are removed so the cluster matches it, other postgresql settings are left to the
+	// operator defaults
+	delete(spec, "imageName")
+	delete(spec, "resources")
+	delete(spec, "backup")
+	unstructured.RemoveNestedField(spec, "postgresql", "parameters")
+	for field, value := range updatableClusterSpec(cfg) {
+		if field == "postgresql" {
+			parameters, _, _ := unstructured.NestedMap(value.(map[string]any), "parameters")
+			if err = unstructured.SetNestedMap(spec, parameters, "postgresql", "parameters"); err != nil {
+				return err
+			}
+			continue
+		}
+		spec[field] = value
+	}
+	if err = unstructured.SetNestedField(spec, cfg.Storage.Size, "storage", "size"); err != nil {
+		return err
+	}
+	if hasWal {
+		if err = unstructured.SetNestedField(spec, cfg.WalStorage.Size, "walStorage", "size"); err != nil {
 			return err
 		}
 	}
+	if err = unstructured.SetNestedMap(cluster.Object, spec, "spec"); err != nil {
+		return err
+	}
 
-	Info("Backend initialized")
+	if err = configureBackupStore(cfg); err != nil {
+		return err
+	}
+
+	if _, err = clusters.Update(context.Background(), cluster, metav1.UpdateOptions{}); err != nil {
+		err = fmt.Errorf("error updating %s: %w", name, err)
+		return err
+	}
+
+	if err = configureScheduledBackup(cfg); err != nil {
+		err = fmt.Errorf("error configuring scheduled backup: %w", err)
+		return err
+	}
+
+	if err = configurePoolers(cfg, activeClusterName()); err != nil {
+		err = fmt.Errorf("error configuring poolers: %w", err)
+		return err
+	}
+
+	// Extensions are only created at bootstrap, so create any new ones on the primary
+	if len(cfg.Extensions) > 0 {
+		if err = createExtensions(cfg); err != nil {
+			err = fmt.Errorf("error creating extensions: %w", err)
+			return err
+		}
+	}
+
+	Info("CloudNative PG Cluster updated")
+	return nil
+}
+
+// createExtensions runs the extension statements with psql on the primary instance
+func createExtensions(cfg models.Backend) error {
+	Debug("Creating extensions on primary")
+
+	clientset, err := kubernetesDefaultClient()
+	if err != nil {
+		return err
+	}
+	primary, err := primaryPod(clientset)
+	if err != nil {
+		return err
+	}
+
+	command := []string{"psql", "-v", "ON_ERROR_STOP=1", "-d", cfg.Database}
+	for _, sql := range extensionSQL(cfg.Extensions) {
+		command = append(command, "-c", sql)
+	}
+	var stderr bytes.Buffer
+	err = execInPod(clientset, primary, "postgres", command, streams{
+		stdout: io.Discard,
+		stderr: &stderr,
+	})
+	if err != nil {
+		err = fmt.Errorf("error running psql in %s: %w: %s", primary, err, strings.TrimSpace(stderr.String()))
+		return err
+	}
+
+	Debug("Extensions created")
 	return nil
 }
 
-// InitBackend creates a job in the created frontend container to run migrations
-func initDjangoBackend() error {
-	Debug("Starting django backend migrations job")
+// primaryPod returns the name of the primary instance pod of the active cluster
+func primaryPod(clientset *kubernetes.Clientset) (string, error) {
+	pods, err := clientset.CoreV1().Pods(AppNamespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: "cnpg.io/cluster=" + activeClusterName() + ",role=primary",
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(pods.Items) == 0 {
+		return "", fmt.Errorf("no primary instance found for %s", activeClusterName())
+	}
+
+	return pods.Items[0].Name, nil
+}
+
+// InitBackend creates a job in the created frontend container to run the framework migrations
+func InitBackend(fw Framework) error {
+	Info("Starting backend initialization")
 
-	imgStr := viper.GetString("frontend.image") + ":" + viper.GetString("frontend.version")
 	var backoffLimit int32 = 10
 
 	clientset, err := kubernetesDefaultClient()
@@ -106,6 +300,17 @@ func initDjangoBackend() error {
 		err = fmt.Errorf("error creating default client for init backend: %w", err)
 		return err
 	}
+	cfg, err := loadAppConfig()
+	if err != nil {
+		err = fmt.Errorf("error with frontend config: %w", err)
+		return err
+	}
+
+	template := podTemplate(fw, cfg, workload{
+		name:    "backend-init",
+		command: migrateCommand(fw),
+	})
+	template.Spec.RestartPolicy = corev1.RestartPolicyOnFailure
 
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
@@ -118,203 +323,106 @@ func initDjangoBackend() error {
 		},
 		Spec: batchv1.JobSpec{
 			BackoffLimit: &backoffLimit,
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
-						"app.kubernetes.io/name": "backend-init",
-					},
-				},
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{
-							Name:  "backend-init",
-							Image: imgStr,
-							Command: []string{
-								"/env/bin/python",
-								"/app/manage.py",
-								"migrate",
-							},
-							ImagePullPolicy: corev1.PullNever,
-							Env: []corev1.EnvVar{
-								{
-									Name: "DATABASE_NAME",
-									ValueFrom: &corev1.EnvVarSource{
-										SecretKeyRef: &corev1.SecretKeySelector{
-											LocalObjectReference: corev1.LocalObjectReference{
-												Name: "poc-backend-cluster-app",
-											},
-											Key: "dbname",
-										},
-									},
-								},
-								{
-									Name: "DATABASE_USER",
-									ValueFrom: &corev1.EnvVarSource{
-										SecretKeyRef: &corev1.SecretKeySelector{
-											LocalObjectReference: corev1.LocalObjectReference{
-												Name: "poc-backend-cluster-app",
-											},
-											Key: "username",
-										},
-									},
-								},
-								{
-									Name: "DATABASE_PASSWORD",
-									ValueFrom: &corev1.EnvVarSource{
-										SecretKeyRef: &corev1.SecretKeySelector{
-											LocalObjectReference: corev1.LocalObjectReference{
-												Name: "poc-backend-cluster-app",
-											},
-											Key: "password",
-										},
-									},
-								},
-								{
-									Name: "DATABASE_HOST",
-									ValueFrom: &corev1.EnvVarSource{
-										SecretKeyRef: &corev1.SecretKeySelector{
-											LocalObjectReference: corev1.LocalObjectReference{
-												Name: "poc-backend-cluster-app",
-											},
-											Key: "host",
-										},
-									},
-								},
-							},
-						},
+			Template:     template,
+		},
+	}
+
+	// Frameworks with a dedicated migration image get the migrations directory mounted from a configmap
+	if runner, ok := fw.(migrationRunner); ok {
+		if err = migrationsConfigMap(clientset, migrationsDir(runner)); err != nil {
+			err = fmt.Errorf("error creating migrations configmap: %w", err)
+			return err
+		}
+
+		container := &job.Spec.Template.Spec.Containers[0]
+		container.Image = runner.MigrateImage()
+		// The migration image isn't a buildpack image, so the command runs without the launcher
+		container.Command = migrateCommand(fw)
+		container.ImagePullPolicy = corev1.PullIfNotPresent
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      "migrations",
+			MountPath: MigrationsMountPath,
+			ReadOnly:  true,
+		})
+		job.Spec.Template.Spec.Volumes = append(job.Spec.Template.Spec.Volumes, corev1.Volume{
+			Name: "migrations",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: "backend-migrations",
 					},
-					RestartPolicy: corev1.RestartPolicyOnFailure,
 				},
 			},
-		},
+		})
 	}
 
-	if _, err = clientset.BatchV1().Jobs("app").Create(context.Background(), job, metav1.CreateOptions{}); err != nil {
-		err = fmt.Errorf("error creating backend-init job: %w", err)
+	if err = recreateJob(clientset, job); err != nil {
+		err = fmt.Errorf("error initializing %s backend: error creating backend-init job: %w", fw.Name(), err)
 		return err
 	}
 
-	Debug("Django backend migration job started")
+	Info("Backend initialized")
 	return nil
 }
 
-func initRORBackend() error {
-	Debug("Starting Ruby on Rails backend migration job")
+// migrationsConfigMap creates the backend-migrations configmap from the files in dir
+func migrationsConfigMap(clientset *kubernetes.Clientset, dir string) error {
+	msg := fmt.Sprintf("Creating migrations configmap from %s", dir)
+	Debug(msg)
 
-	imgStr := viper.GetString("frontend.image") + ":" + viper.GetString("frontend.version")
-	var backoffLimit int32 = 10
-
-	clientset, err := kubernetesDefaultClient()
+	files, err := os.ReadDir(dir)
 	if err != nil {
-		err = fmt.Errorf("error creating default client for init backend: %w", err)
+		err = fmt.Errorf("error reading migrations directory: %w", err)
 		return err
 	}
 
-	job := &batchv1.Job{
+	// Configmaps are limited to 1MiB
+	data := map[string]string{}
+	size := 0
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			err = fmt.Errorf("error reading migration %s: %w", f.Name(), err)
+			return err
+		}
+		size += len(content)
+		data[f.Name()] = string(content)
+	}
+	if size > 1024*1024 {
+		err = fmt.Errorf("migrations in %s are %d bytes, larger than the 1MiB configmap limit", dir, size)
+		return err
+	}
+
+	configMap := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "backend-init",
+			Name:      "backend-migrations",
 			Namespace: "app",
 			Labels: map[string]string{
-				"app.kubernetes.io/component": "job",
+				"app.kubernetes.io/component": "configmap",
 				"app.kubernetes.io/name":      "backend-init",
 			},
 		},
-		Spec: batchv1.JobSpec{
-			BackoffLimit: &backoffLimit,
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
-						"app.kubernetes.io/name": "backend-init",
-					},
-				},
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{
-							Name:  "backend-init",
-							Image: imgStr,
-							Command: []string{
-								"bundle",
-								"exec",
-								"rails",
-								"db:prepare",
-							},
-							ImagePullPolicy: corev1.PullNever,
-							Env: []corev1.EnvVar{
-								{
-									Name: "DATABASE_NAME",
-									ValueFrom: &corev1.EnvVarSource{
-										SecretKeyRef: &corev1.SecretKeySelector{
-											LocalObjectReference: corev1.LocalObjectReference{
-												Name: "poc-backend-cluster-app",
-											},
-											Key: "dbname",
-										},
-									},
-								},
-								{
-									Name: "DATABASE_USER",
-									ValueFrom: &corev1.EnvVarSource{
-										SecretKeyRef: &corev1.SecretKeySelector{
-											LocalObjectReference: corev1.LocalObjectReference{
-												Name: "poc-backend-cluster-app",
-											},
-											Key: "username",
-										},
-									},
-								},
-								{
-									Name: "DATABASE_PASSWORD",
-									ValueFrom: &corev1.EnvVarSource{
-										SecretKeyRef: &corev1.SecretKeySelector{
-											LocalObjectReference: corev1.LocalObjectReference{
-												Name: "poc-backend-cluster-app",
-											},
-											Key: "password",
-										},
-									},
-								},
-								{
-									Name: "DATABASE_HOST",
-									ValueFrom: &corev1.EnvVarSource{
-										SecretKeyRef: &corev1.SecretKeySelector{
-											LocalObjectReference: corev1.LocalObjectReference{
-												Name: "poc-backend-cluster-app",
-											},
-											Key: "host",
-										},
-									},
-								},
-								{
-									Name: "SECRET_KEY",
-									ValueFrom: &corev1.EnvVarSource{
-										SecretKeyRef: &corev1.SecretKeySelector{
-											LocalObjectReference: corev1.LocalObjectReference{
-												Name: "secret-key",
-											},
-											Key: "key",
-										},
-									},
-								},
-							},
-						},
-					},
-					RestartPolicy: corev1.RestartPolicyOnFailure,
-				},
-			},
-		},
+		Data: data,
 	}
 
-	if _, err = clientset.BatchV1().Jobs("app").Create(context.Background(), job, metav1.CreateOptions{}); err != nil {
-		err = fmt.Errorf("error creating backend-init job: %w", err)
+	configMaps := clientset.CoreV1().ConfigMaps("app")
+	_, err = configMaps.Create(context.Background(), configMap, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		_, err = configMaps.Update(context.Background(), configMap, metav1.UpdateOptions{})
+	}
+	if err != nil {
 		return err
 	}
 
-	Debug("Ruby on Rails Backend migration job started")
+	Debug("Migrations configmap created")
 	return nil
 }
 
-// InstallBackend installs the CNPG operator
-func InstallBackend() error {
+// Install installs the CNPG operator
+func (cnpgBackend) Install() error {
 	Debug("Installing CNPG operator")
 
 	// Use embedded cnpg-1.24.0.yaml file to pass to kubectl apply
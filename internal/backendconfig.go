@@ -0,0 +1,288 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/harvey-earth/pocdeploy/internal/models"
+)
+
+// PostgresImage is the CloudNative PG operand image, tagged with backend.postgres_version
+const PostgresImage = "ghcr.io/cloudnative-pg/postgresql"
+
+// retentionPolicy matches the CloudNative PG backup retention format
+var retentionPolicy = regexp.MustCompile(`^[1-9][0-9]*[dwm]$`)
+
+// postgresIdentifier matches names that can be used unquoted for databases, roles and extensions
+var postgresIdentifier = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+// loadBackendConfig reads the backend config section, setting defaults and validating it
+func loadBackendConfig() (models.Backend, error) {
+	var cfg models.Backend
+	if err := viper.UnmarshalKey("backend", &cfg); err != nil {
+		err = fmt.Errorf("error reading backend config: %w", err)
+		return cfg, err
+	}
+
+	cfg.Type = backendType()
+	if cfg.Instances == 0 {
+		cfg.Instances = 3
+	}
+	if cfg.Storage.Size == "" {
+		cfg.Storage.Size = "1Gi"
+	}
+	if cfg.Database == "" {
+		cfg.Database = "app"
+	}
+	if cfg.Owner == "" {
+		cfg.Owner = cfg.Database
+	}
+	if cfg.Backup.MinIO {
+		if cfg.Backup.Destination == "" {
+			cfg.Backup.Destination = "s3://" + MinIOBucket + "/"
+		}
+		if cfg.Backup.Endpoint == "" {
+			cfg.Backup.Endpoint = fmt.Sprintf("http://%s.%s.svc.cluster.local:9000", MinIOName, AppNamespace)
+		}
+		if cfg.Backup.MinIOSize == "" {
+			cfg.Backup.MinIOSize = "5Gi"
+		}
+	}
+	if cfg.Pooler.Instances == 0 {
+		cfg.Pooler.Instances = 1
+	}
+	if cfg.Pooler.PoolMode == "" {
+		cfg.Pooler.PoolMode = "session"
+	}
+	if cfg.Backup.Schedule == "" {
+		cfg.Backup.Schedule = "0 0 0 * * *"
+	}
+	if cfg.Backup.Retention == "" {
+		cfg.Backup.Retention = "7d"
+	}
+
+	if err := validateBackendConfig(cfg); err != nil {
+		return cfg, err
+	}
+	b, err := getBackend(cfg.Type)
+	if err != nil {
+		return cfg, err
+	}
+	if err = b.Validate(cfg); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+// validateBackendConfig checks the values the operator would otherwise reject after the cluster is created
+func validateBackendConfig(cfg models.Backend) error {
+	if cfg.Instances < 1 {
+		return fmt.Errorf("backend.instances must be at least 1, got %d", cfg.Instances)
+	}
+	if _, err := resource.ParseQuantity(cfg.Storage.Size); err != nil {
+		return fmt.Errorf("backend.storage.size %q is not a valid quantity: %w", cfg.Storage.Size, err)
+	}
+	if cfg.WalStorage.Size != "" {
+		if _, err := resource.ParseQuantity(cfg.WalStorage.Size); err != nil {
+			return fmt.Errorf("backend.wal_storage.size %q is not a valid quantity: %w", cfg.WalStorage.Size, err)
+		}
+	} else if cfg.WalStorage.StorageClass != "" {
+		return fmt.Errorf("backend.wal_storage.size must be set with backend.wal_storage.storage_class")
+	}
+	if cfg.Image != "" && cfg.PostgresVersion != 0 {
+		return fmt.Errorf("backend.image and backend.postgres_version can't both be set")
+	}
+	if cfg.PostgresVersion != 0 && (cfg.PostgresVersion < 13 || cfg.PostgresVersion > 17) {
+		return fmt.Errorf("backend.postgres_version must be between 13 and 17, got %d", cfg.PostgresVersion)
+	}
+	for name, value := range cfg.Resources.Requests {
+		if _, err := resource.ParseQuantity(value); err != nil {
+			return fmt.Errorf("backend.resources.requests.%s %q is not a valid quantity: %w", name, value, err)
+		}
+	}
+	for name, value := range cfg.Resources.Limits {
+		if _, err := resource.ParseQuantity(value); err != nil {
+			return fmt.Errorf("backend.resources.limits.%s %q is not a valid quantity: %w", name, value, err)
+		}
+	}
+	if !postgresIdentifier.MatchString(cfg.Database) {
+		return fmt.Errorf("backend.database %q is not a valid database name", cfg.Database)
+	}
+	if !postgresIdentifier.MatchString(cfg.Owner) {
+		return fmt.Errorf("backend.owner %q is not a valid role name", cfg.Owner)
+	}
+	for _, ext := range cfg.Extensions {
+		if !postgresIdentifier.MatchString(ext) {
+			return fmt.Errorf("backend.extensions entry %q is not a valid extension name", ext)
+		}
+	}
+
+	if cfg.Pooler.Enabled {
+		if cfg.Pooler.Instances < 1 {
+			return fmt.Errorf("backend.pooler.instances must be at least 1, got %d", cfg.Pooler.Instances)
+		}
+		if cfg.Pooler.PoolMode != "session" && cfg.Pooler.PoolMode != "transaction" {
+			return fmt.Errorf("backend.pooler.pool_mode must be session or transaction, got %q", cfg.Pooler.PoolMode)
+		}
+		if cfg.Pooler.DefaultPoolSize < 0 || cfg.Pooler.MaxClientConn < 0 {
+			return fmt.Errorf("backend.pooler.default_pool_size and backend.pooler.max_client_conn can't be negative")
+		}
+	}
+
+	seeds := 0
+	for _, seed := range []string{cfg.Seed.File, cfg.Seed.Fixture, cfg.Seed.Task} {
+		if seed != "" {
+			seeds++
+		}
+	}
+	if seeds > 1 {
+		return fmt.Errorf("only one of backend.seed.file, backend.seed.fixture and backend.seed.task can be set")
+	}
+	if cfg.Seed.File != "" {
+		if ext := filepath.Ext(cfg.Seed.File); ext != ".sql" && ext != ".dump" {
+			return fmt.Errorf("backend.seed.file %q must be a .sql or .dump file", cfg.Seed.File)
+		}
+		if _, err := os.Stat(cfg.Seed.File); err != nil {
+			return fmt.Errorf("backend.seed.file: %w", err)
+		}
+	}
+
+	if backupEnabled(cfg) {
+		if !strings.HasPrefix(cfg.Backup.Destination, "s3://") {
+			return fmt.Errorf("backend.backup.destination %q must be an s3:// URL", cfg.Backup.Destination)
+		}
+		if !cfg.Backup.MinIO && (cfg.Backup.AccessKeyID == "" || cfg.Backup.SecretAccessKey == "") {
+			return fmt.Errorf("backend.backup.access_key_id and backend.backup.secret_access_key must be set unless backend.backup.minio is true")
+		}
+		if len(strings.Fields(cfg.Backup.Schedule)) != 6 {
+			return fmt.Errorf("backend.backup.schedule %q must have six fields, starting with seconds", cfg.Backup.Schedule)
+		}
+		if !retentionPolicy.MatchString(cfg.Backup.Retention) {
+			return fmt.Errorf("backend.backup.retention %q must be a number of days, weeks or months (ex. 30d)", cfg.Backup.Retention)
+		}
+		if cfg.Backup.MinIO {
+			if _, err := resource.ParseQuantity(cfg.Backup.MinIOSize); err != nil {
+				return fmt.Errorf("backend.backup.minio_size %q is not a valid quantity: %w", cfg.Backup.MinIOSize, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// clusterSpec renders the spec of the CloudNative PG Cluster, including the bootstrap section
+func clusterSpec(cfg models.Backend) map[string]any {
+	spec := updatableClusterSpec(cfg)
+
+	spec["storage"] = storageSpec(cfg.Storage)
+	if cfg.WalStorage.Size != "" {
+		spec["walStorage"] = storageSpec(cfg.WalStorage)
+	}
+
+	initdb := map[string]any{
+		"database": cfg.Database,
+		"owner":    cfg.Owner,
+	}
+	if len(cfg.Extensions) > 0 {
+		sql := []any{}
+		for _, statement := range extensionSQL(cfg.Extensions) {
+			sql = append(sql, statement)
+		}
+		initdb["postInitApplicationSQL"] = sql
+	}
+	spec["bootstrap"] = map[string]any{
+		"initdb": initdb,
+	}
+
+	return spec
+}
+
+// updatableClusterSpec renders the fields of the Cluster spec that can be changed on a running cluster
+func updatableClusterSpec(cfg models.Backend) map[string]any {
+	spec := map[string]any{
+		"instances": int64(cfg.Instances),
+	}
+
+	if image := postgresImage(cfg); image != "" {
+		spec["imageName"] = image
+	}
+
+	if len(cfg.Parameters) > 0 {
+		parameters := map[string]any{}
+		for name, value := range cfg.Parameters {
+			parameters[name] = value
+		}
+		spec["postgresql"] = map[string]any{
+			"parameters": parameters,
+		}
+	}
+
+	resources := map[string]any{}
+	if len(cfg.Resources.Requests) > 0 {
+		resources["requests"] = quantities(cfg.Resources.Requests)
+	}
+	if len(cfg.Resources.Limits) > 0 {
+		resources["limits"] = quantities(cfg.Resources.Limits)
+	}
+	if len(resources) > 0 {
+		spec["resources"] = resources
+	}
+
+	if backupEnabled(cfg) {
+		spec["backup"] = backupSpec(cfg.Backup)
+	}
+
+	return spec
+}
+
+// postgresImage returns backend.image, the operand image for backend.postgres_version, or an empty string to use the
+// operator default
+func postgresImage(cfg models.Backend) string {
+	if cfg.Image != "" {
+		return cfg.Image
+	}
+	if cfg.PostgresVersion != 0 {
+		return fmt.Sprintf("%s:%d", PostgresImage, cfg.PostgresVersion)
+	}
+
+	return ""
+}
+
+// storageSpec renders a Cluster storage section
+func storageSpec(storage models.BackendStorage) map[string]any {
+	spec := map[string]any{
+		"size": storage.Size,
+	}
+	if storage.StorageClass != "" {
+		spec["storageClass"] = storage.StorageClass
+	}
+
+	return spec
+}
+
+// quantities converts a map of resource quantities to the unstructured form
+func quantities(values map[string]string) map[string]any {
+	out := map[string]any{}
+	for name, value := range values {
+		out[name] = value
+	}
+
+	return out
+}
+
+// extensionSQL returns the statements creating each extension, which are safe to run again
+func extensionSQL(extensions []string) []string {
+	sql := []string{}
+	for _, ext := range extensions {
+		sql = append(sql, fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s", ext))
+	}
+
+	return sql
+}
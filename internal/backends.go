@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/harvey-earth/pocdeploy/internal/models"
+)
+
+// databaseBackend contains the steps that differ between database backends. Every backend creates the secret
+// returned by databaseSecretName with the dbname, username, password, host and port keys, so the frontend
+// receives the same DATABASE_* variables.
+type databaseBackend interface {
+	// Name returns the backend.type value that selects the backend
+	Name() string
+	// Scheme returns the DATABASE_URL scheme, which is also set as DATABASE_ENGINE
+	Scheme() string
+	// Validate returns an error for backend config the backend doesn't support
+	Validate(cfg models.Backend) error
+	// Install installs the operator
+	Install() error
+	// Configure creates the database cluster and its credentials secret
+	Configure(cfg models.Backend) error
+	// Update applies the backend config to the running cluster
+	Update(cfg models.Backend) error
+	// Status returns the state of the running cluster
+	Status() (models.ComponentStatus, error)
+}
+
+// backends holds every registered database backend by name
+var backends = map[string]databaseBackend{}
+
+// registerBackend adds a backend to the registry, it is called from init in each backend file
+func registerBackend(b databaseBackend) {
+	backends[b.Name()] = b
+}
+
+// getBackend returns the backend registered for backend type t
+func getBackend(t string) (databaseBackend, error) {
+	b, ok := backends[t]
+	if !ok {
+		names := []string{}
+		for name := range backends {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		err := fmt.Errorf("unknown backend type %q, supported types are: %s", t, strings.Join(names, ", "))
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// backendType returns backend.type, which defaults to cnpg
+func backendType() string {
+	if t := viper.GetString("backend.type"); t != "" {
+		return t
+	}
+
+	return "cnpg"
+}
+
+// databaseScheme returns the DATABASE_URL scheme of the configured backend, the config has already been validated
+// by the time pods are created so an unknown type falls back to postgresql
+func databaseScheme() string {
+	if b, err := getBackend(backendType()); err == nil {
+		return b.Scheme()
+	}
+
+	return "postgresql"
+}
+
+// requireCNPG returns an error naming feature if the backend is not CloudNative PG
+func requireCNPG(feature string) error {
+	if t := backendType(); t != "cnpg" {
+		return fmt.Errorf("%s is not supported for the %s backend", feature, t)
+	}
+
+	return nil
+}
+
+// CheckBackendConfig validates the backend config section so mistakes are caught before anything is created
+func CheckBackendConfig() error {
+	_, err := loadBackendConfig()
+	return err
+}
+
+// InstallBackend installs the operator of the configured backend
+func InstallBackend() error {
+	b, err := getBackend(backendType())
+	if err != nil {
+		return err
+	}
+
+	return b.Install()
+}
+
+// ConfigureBackend creates the database cluster of the configured backend
+func ConfigureBackend() error {
+	cfg, err := loadBackendConfig()
+	if err != nil {
+		return err
+	}
+	b, err := getBackend(cfg.Type)
+	if err != nil {
+		return err
+	}
+
+	return b.Configure(cfg)
+}
+
+// UpdateBackend applies the backend config to the running database cluster without recreating it
+func UpdateBackend() error {
+	cfg, err := loadBackendConfig()
+	if err != nil {
+		return err
+	}
+	b, err := getBackend(cfg.Type)
+	if err != nil {
+		return err
+	}
+
+	return b.Update(cfg)
+}
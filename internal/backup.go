@@ -0,0 +1,540 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/harvey-earth/pocdeploy/internal/models"
+)
+
+// BackupSecretName is the secret holding the object store credentials used by CloudNative PG
+const BackupSecretName = "backup-credentials"
+
+// ScheduledBackupName is the name of the CloudNative PG ScheduledBackup
+const ScheduledBackupName = ClusterName + "-scheduled"
+
+// MinIOName is the name of the in-cluster MinIO deployment and service
+const MinIOName = "minio"
+
+// MinIOBucket is the bucket backups are written to in MinIO
+const MinIOBucket = "backups"
+
+// MinIOImage is the MinIO server image
+const MinIOImage = "quay.io/minio/minio:RELEASE.2024-10-13T13-34-11Z"
+
+// MinIOClientImage is the MinIO client image used to create the bucket
+const MinIOClientImage = "quay.io/minio/mc:RELEASE.2024-10-08T09-37-26Z"
+
+// backupGVR is the CloudNative PG Backup resource
+var backupGVR = schema.GroupVersionResource{
+	Group:    "postgresql.cnpg.io",
+	Version:  "v1",
+	Resource: "backups",
+}
+
+// scheduledBackupGVR is the CloudNative PG ScheduledBackup resource
+var scheduledBackupGVR = schema.GroupVersionResource{
+	Group:    "postgresql.cnpg.io",
+	Version:  "v1",
+	Resource: "scheduledbackups",
+}
+
+// backupEnabled returns true if backend.backup has a destination, which MinIO sets by default
+func backupEnabled(cfg models.Backend) bool {
+	return cfg.Backup.Destination != ""
+}
+
+// backupSpec renders the backup section of the Cluster spec
+func backupSpec(backup models.BackendBackup) map[string]any {
+	credential := func(key string) map[string]any {
+		return map[string]any{
+			"name": BackupSecretName,
+			"key":  key,
+		}
+	}
+
+	credentials := map[string]any{
+		"accessKeyId":     credential("ACCESS_KEY_ID"),
+		"secretAccessKey": credential("ACCESS_SECRET_KEY"),
+	}
+	if backup.Region != "" {
+		credentials["region"] = credential("REGION")
+	}
+
+	store := map[string]any{
+		"destinationPath": backup.Destination,
+		"s3Credentials":   credentials,
+		"wal": map[string]any{
+			"compression": "gzip",
+		},
+		"data": map[string]any{
+			"compression": "gzip",
+		},
+	}
+	if backup.Endpoint != "" {
+		store["endpointURL"] = backup.Endpoint
+	}
+
+	return map[string]any{
+		"barmanObjectStore": store,
+		"retentionPolicy":   backup.Retention,
+	}
+}
+
+// configureBackupStore creates the backup credentials secret, and MinIO if it is enabled, before the cluster
+// references them
+func configureBackupStore(cfg models.Backend) error {
+	if !backupEnabled(cfg) {
+		return nil
+	}
+	Info("Configuring backup object store")
+
+	clientset, err := kubernetesDefaultClient()
+	if err != nil {
+		return err
+	}
+
+	if err = backupSecret(clientset, cfg.Backup); err != nil {
+		err = fmt.Errorf("error creating %s secret: %w", BackupSecretName, err)
+		return err
+	}
+
+	if cfg.Backup.MinIO {
+		if err = deployMinIO(clientset, cfg.Backup); err != nil {
+			err = fmt.Errorf("error deploying MinIO: %w", err)
+			return err
+		}
+	}
+
+	Info("Backup object store configured")
+	return nil
+}
+
+// backupSecret creates or updates the backup credentials secret. MinIO credentials are generated once and reused.
+func backupSecret(clientset *kubernetes.Clientset, backup models.BackendBackup) error {
+	secrets := clientset.CoreV1().Secrets(AppNamespace)
+
+	accessKey, secretKey := backup.AccessKeyID, backup.SecretAccessKey
+	if backup.MinIO && (accessKey == "" || secretKey == "") {
+		existing, err := secrets.Get(context.Background(), BackupSecretName, metav1.GetOptions{})
+		if err == nil && len(existing.Data["ACCESS_SECRET_KEY"]) > 0 {
+			accessKey = string(existing.Data["ACCESS_KEY_ID"])
+			secretKey = string(existing.Data["ACCESS_SECRET_KEY"])
+		} else if err != nil && !errors.IsNotFound(err) {
+			return err
+		} else {
+			accessKey = "pocdeploy"
+			if secretKey, err = generateRandomString(32); err != nil {
+				err = fmt.Errorf("error generating MinIO password: %w", err)
+				return err
+			}
+		}
+	}
+
+	data := map[string]string{
+		"ACCESS_KEY_ID":     accessKey,
+		"ACCESS_SECRET_KEY": secretKey,
+	}
+	if backup.Region != "" {
+		data["REGION"] = backup.Region
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      BackupSecretName,
+			Namespace: AppNamespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/component": "secret",
+				"app.kubernetes.io/name":      "backup",
+			},
+		},
+		StringData: data,
+		Type:       corev1.SecretTypeOpaque,
+	}
+
+	_, err := secrets.Create(context.Background(), secret, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		_, err = secrets.Update(context.Background(), secret, metav1.UpdateOptions{})
+	}
+
+	return err
+}
+
+// deployMinIO creates a single node MinIO with a persistent volume and a job that creates the backups bucket
+func deployMinIO(clientset *kubernetes.Clientset, backup models.BackendBackup) error {
+	Debug("Deploying MinIO")
+	labels := map[string]string{
+		"app.kubernetes.io/component": "backup",
+		"app.kubernetes.io/name":      MinIOName,
+	}
+	selector := map[string]string{
+		"app.kubernetes.io/name": MinIOName,
+	}
+	credentialsEnv := func(name, key string) corev1.EnvVar {
+		return corev1.EnvVar{
+			Name: name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: BackupSecretName,
+					},
+					Key: key,
+				},
+			},
+		}
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      MinIOName + "-data",
+			Namespace: AppNamespace,
+			Labels:    labels,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: resource.MustParse(backup.MinIOSize),
+				},
+			},
+		},
+	}
+	if _, err := clientset.CoreV1().PersistentVolumeClaims(AppNamespace).Create(context.Background(), pvc, metav1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+		err = fmt.Errorf("error creating MinIO volume claim: %w", err)
+		return err
+	}
+
+	var reps int32 = 1
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      MinIOName,
+			Namespace: AppNamespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &reps,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: selector,
+			},
+			// The volume can only be mounted by one pod
+			Strategy: appsv1.DeploymentStrategy{
+				Type: appsv1.RecreateDeploymentStrategyType,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: selector,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  MinIOName,
+							Image: MinIOImage,
+							Args:  []string{"server", "/data"},
+							Env: []corev1.EnvVar{
+								credentialsEnv("MINIO_ROOT_USER", "ACCESS_KEY_ID"),
+								credentialsEnv("MINIO_ROOT_PASSWORD", "ACCESS_SECRET_KEY"),
+							},
+							Ports: []corev1.ContainerPort{
+								{
+									Name:          "s3",
+									ContainerPort: 9000,
+									Protocol:      corev1.ProtocolTCP,
+								},
+							},
+							ReadinessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									HTTPGet: &corev1.HTTPGetAction{
+										Path: "/minio/health/ready",
+										Port: intstr.FromInt32(9000),
+									},
+								},
+								PeriodSeconds: 10,
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      "data",
+									MountPath: "/data",
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "data",
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+									ClaimName: pvc.Name,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if _, err := clientset.AppsV1().Deployments(AppNamespace).Create(context.Background(), deployment, metav1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+		err = fmt.Errorf("error creating MinIO deployment: %w", err)
+		return err
+	}
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      MinIOName,
+			Namespace: AppNamespace,
+			Labels:    labels,
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: selector,
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "s3",
+					Port:       9000,
+					TargetPort: intstr.FromInt32(9000),
+					Protocol:   corev1.ProtocolTCP,
+				},
+			},
+		},
+	}
+	if _, err := clientset.CoreV1().Services(AppNamespace).Create(context.Background(), service, metav1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+		err = fmt.Errorf("error creating MinIO service: %w", err)
+		return err
+	}
+
+	// The job retries until MinIO is ready
+	var backoffLimit int32 = 10
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      MinIOName + "-bucket",
+			Namespace: AppNamespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/component": "job",
+				"app.kubernetes.io/name":      MinIOName + "-bucket",
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "mc",
+							Image: MinIOClientImage,
+							Command: []string{
+								"/bin/sh",
+								"-c",
+								fmt.Sprintf("mc alias set local http://%s:9000 \"$ACCESS_KEY_ID\" \"$ACCESS_SECRET_KEY\" && mc mb --ignore-existing local/%s", MinIOName, MinIOBucket),
+							},
+							Env: []corev1.EnvVar{
+								credentialsEnv("ACCESS_KEY_ID", "ACCESS_KEY_ID"),
+								credentialsEnv("ACCESS_SECRET_KEY", "ACCESS_SECRET_KEY"),
+							},
+						},
+					},
+					RestartPolicy: corev1.RestartPolicyOnFailure,
+				},
+			},
+		},
+	}
+	if err := recreateJob(clientset, job); err != nil {
+		err = fmt.Errorf("error creating MinIO bucket job: %w", err)
+		return err
+	}
+
+	Debug("MinIO deployed")
+	return nil
+}
+
+// configureScheduledBackup creates or updates the ScheduledBackup, or deletes it if backups are disabled
+func configureScheduledBackup(cfg models.Backend) error {
+	clientset, err := kubernetesDynamicClient()
+	if err != nil {
+		return err
+	}
+	scheduledBackups := clientset.Resource(scheduledBackupGVR).Namespace(AppNamespace)
+
+	if !backupEnabled(cfg) {
+		err = scheduledBackups.Delete(context.Background(), ScheduledBackupName, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+	Debug("Configuring scheduled backup")
+
+	spec := map[string]any{
+		"schedule":             cfg.Backup.Schedule,
+		"backupOwnerReference": "self",
+		"method":               "barmanObjectStore",
+		"cluster": map[string]any{
+			"name": activeClusterName(),
+		},
+	}
+
+	existing, err := scheduledBackups.Get(context.Background(), ScheduledBackupName, metav1.GetOptions{})
+	if err == nil {
+		if err = unstructured.SetNestedMap(existing.Object, spec, "spec"); err != nil {
+			return err
+		}
+		_, err = scheduledBackups.Update(context.Background(), existing, metav1.UpdateOptions{})
+		return err
+	} else if !errors.IsNotFound(err) {
+		return err
+	}
+
+	scheduledBackup := &unstructured.Unstructured{
+		Object: map[string]any{
+			"apiVersion": "postgresql.cnpg.io/v1",
+			"kind":       "ScheduledBackup",
+			"metadata": map[string]any{
+				"name":      ScheduledBackupName,
+				"namespace": AppNamespace,
+				"labels": map[string]any{
+					"app.kubernetes.io/component": "backup",
+					"app.kubernetes.io/name":      "backend",
+				},
+			},
+			"spec": spec,
+		},
+	}
+
+	for i := 1; ; i++ {
+		if _, err := scheduledBackups.Create(context.Background(), scheduledBackup, metav1.CreateOptions{}); err != nil {
+			msg := fmt.Sprintf("Retrying scheduled backup %d of %d", i, MaxRetries)
+			Debug(msg)
+			time.Sleep(time.Duration(i*2) * time.Second)
+			if i >= MaxRetries {
+				err = fmt.Errorf("end of retries for scheduled backup: %w", err)
+				return err
+			}
+		} else {
+			break
+		}
+	}
+
+	Debug("Scheduled backup configured")
+	return nil
+}
+
+// CreateBackup starts an on-demand backup of the backend cluster and returns its name.
+// If wait is set, it returns once the backup has completed.
+func CreateBackup(wait bool) (string, error) {
+	if err := requireCNPG("backup now"); err != nil {
+		return "", err
+	}
+	cfg, err := loadBackendConfig()
+	if err != nil {
+		return "", err
+	}
+	if !backupEnabled(cfg) {
+		return "", fmt.Errorf("backups are not configured, set backend.backup.destination or backend.backup.minio")
+	}
+
+	clientset, err := kubernetesDynamicClient()
+	if err != nil {
+		return "", err
+	}
+	backups := clientset.Resource(backupGVR).Namespace(AppNamespace)
+
+	name := fmt.Sprintf("%s-%s", activeClusterName(), time.Now().UTC().Format("20060102150405"))
+	backup := &unstructured.Unstructured{
+		Object: map[string]any{
+			"apiVersion": "postgresql.cnpg.io/v1",
+			"kind":       "Backup",
+			"metadata": map[string]any{
+				"name":      name,
+				"namespace": AppNamespace,
+				"labels": map[string]any{
+					"app.kubernetes.io/component": "backup",
+					"app.kubernetes.io/name":      "backend",
+				},
+			},
+			"spec": map[string]any{
+				"method": "barmanObjectStore",
+				"cluster": map[string]any{
+					"name": activeClusterName(),
+				},
+			},
+		},
+	}
+	if _, err = backups.Create(context.Background(), backup, metav1.CreateOptions{}); err != nil {
+		err = fmt.Errorf("error creating backup %s: %w", name, err)
+		return "", err
+	}
+	msg := fmt.Sprintf("Backup %s started", name)
+	Info(msg)
+
+	if !wait {
+		return name, nil
+	}
+
+	for i := 1; ; i++ {
+		current, err := backups.Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			return name, err
+		}
+		phase, _, _ := unstructured.NestedString(current.Object, "status", "phase")
+		switch phase {
+		case "completed":
+			return name, nil
+		case "failed":
+			reason, _, _ := unstructured.NestedString(current.Object, "status", "error")
+			return name, fmt.Errorf("backup %s failed: %s", name, reason)
+		}
+
+		msg := fmt.Sprintf("Waiting for backup %s in phase %q %d of %d", name, phase, i, MaxRetries)
+		Debug(msg)
+		if i >= MaxRetries {
+			return name, fmt.Errorf("end of retries waiting for backup %s", name)
+		}
+		time.Sleep(time.Duration(i*2) * time.Second)
+	}
+}
+
+// ListBackups returns the backups of every backend cluster, including those replaced by a restore, oldest first
+func ListBackups() ([]models.Backup, error) {
+	if err := requireCNPG("backup list"); err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetesDynamicClient()
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := clientset.Resource(backupGVR).Namespace(AppNamespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		err = fmt.Errorf("error listing backups: %w", err)
+		return nil, err
+	}
+
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].GetCreationTimestamp().Time.Before(list.Items[j].GetCreationTimestamp().Time)
+	})
+
+	backups := []models.Backup{}
+	for _, item := range list.Items {
+		backup := models.Backup{
+			Name: item.GetName(),
+		}
+		backup.Cluster, _, _ = unstructured.NestedString(item.Object, "spec", "cluster", "name")
+		backup.Phase, _, _ = unstructured.NestedString(item.Object, "status", "phase")
+		backup.Started, _, _ = unstructured.NestedString(item.Object, "status", "startedAt")
+		backup.Stopped, _, _ = unstructured.NestedString(item.Object, "status", "stoppedAt")
+		backup.Error, _, _ = unstructured.NestedString(item.Object, "status", "error")
+		backups = append(backups, backup)
+	}
+
+	return backups, nil
+}
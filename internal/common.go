@@ -1,12 +1,21 @@
 package internal
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
@@ -16,10 +25,31 @@ const MaxRetries = 15
 // PrometheusVersion sets the version of the prometheus operator manifest
 const PrometheusVersion = "0.76.2"
 
+// AppNamespace is the namespace the frontend and backend are deployed to
+const AppNamespace = "app"
+
+// ClusterName is the name of the CloudNative PG cluster created with the deployment, restores create new clusters
+// prefixed with it
+const ClusterName = "poc-backend-cluster"
+
+// SecretKeySecretName is the secret holding the generated application secret key
+const SecretKeySecretName = "secret-key"
+
+// AdminSecretName is the secret holding the frontend admin user credentials
+const AdminSecretName = "admin-credentials"
+
+// FrontendPort is the port the frontend container listens on
+const FrontendPort = 8000
+
+// Creates the kubernetes client config from the default kubeconfig, used directly by the exec and port-forward APIs
+func kubernetesRestConfig() (*rest.Config, error) {
+	kubeconf := filepath.Join(os.Getenv("HOME"), ".kube", "config")
+	return clientcmd.BuildConfigFromFlags("", kubeconf)
+}
+
 // Creates a default kubernetes client
 func kubernetesDefaultClient() (clientset *kubernetes.Clientset, err error) {
-	kubeconf := filepath.Join(os.Getenv("HOME"), ".kube", "config")
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconf)
+	config, err := kubernetesRestConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -33,8 +63,7 @@ func kubernetesDefaultClient() (clientset *kubernetes.Clientset, err error) {
 
 // Creates a dynamic kubernetes client
 func kubernetesDynamicClient() (clientset *dynamic.DynamicClient, err error) {
-	kubeconf := filepath.Join(os.Getenv("HOME"), ".kube", "config")
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconf)
+	config, err := kubernetesRestConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -46,6 +75,62 @@ func kubernetesDynamicClient() (clientset *dynamic.DynamicClient, err error) {
 	return
 }
 
+// recreateJob deletes any previous job with the same name so the job can be run again, then creates it
+func recreateJob(clientset *kubernetes.Clientset, job *batchv1.Job) error {
+	jobs := clientset.BatchV1().Jobs(job.Namespace)
+	propagation := metav1.DeletePropagationBackground
+	err := jobs.Delete(context.Background(), job.Name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !errors.IsNotFound(err) {
+		err = fmt.Errorf("error deleting previous %s job: %w", job.Name, err)
+		return err
+	}
+
+	// The previous job may take a moment to be removed
+	for i := 1; ; i++ {
+		if _, err := jobs.Create(context.Background(), job, metav1.CreateOptions{}); err != nil {
+			msg := fmt.Sprintf("Retrying %s job %d of %d", job.Name, i, MaxRetries)
+			Debug(msg)
+			time.Sleep(time.Duration(i*2) * time.Second)
+			if i >= MaxRetries {
+				err = fmt.Errorf("end of retries for %s job: %w", job.Name, err)
+				return err
+			}
+		} else {
+			break
+		}
+	}
+
+	return nil
+}
+
+// waitForJob waits for the named job to succeed, returning an error if it fails or the retries run out
+func waitForJob(clientset *kubernetes.Clientset, name string) error {
+	jobs := clientset.BatchV1().Jobs(AppNamespace)
+
+	for i := 1; ; i++ {
+		job, err := jobs.Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			err = fmt.Errorf("error getting %s job: %w", name, err)
+			return err
+		}
+		if job.Status.Succeeded > 0 {
+			return nil
+		}
+		for _, condition := range job.Status.Conditions {
+			if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+				return fmt.Errorf("%s job failed: %s", name, condition.Message)
+			}
+		}
+
+		msg := fmt.Sprintf("Waiting for %s job %d of %d", name, i, MaxRetries)
+		Debug(msg)
+		if i >= MaxRetries {
+			return fmt.Errorf("end of retries waiting for %s job", name)
+		}
+		time.Sleep(time.Duration(i*2) * time.Second)
+	}
+}
+
 func writeTempFile(content []byte) (tempfile *os.File, err error) {
 	tempfile, err = os.CreateTemp("", "pocdeploy-*.yaml")
 	if err != nil {
@@ -65,3 +150,22 @@ func writeTempFile(content []byte) (tempfile *os.File, err error) {
 
 	return
 }
+
+// storedPassword returns key from the existing secret so generated passwords survive reconfiguration, or generates a
+// new password that is safe in URLs and SQL strings
+func storedPassword(clientset *kubernetes.Clientset, secret, key string) (string, error) {
+	existing, err := clientset.CoreV1().Secrets(AppNamespace).Get(context.Background(), secret, metav1.GetOptions{})
+	if err == nil && len(existing.Data[key]) > 0 {
+		return string(existing.Data[key]), nil
+	} else if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+
+	b := make([]byte, 24)
+	if _, err = rand.Read(b); err != nil {
+		err = fmt.Errorf("error generating password: %w", err)
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
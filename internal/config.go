@@ -0,0 +1,190 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// secretReference matches ${env:NAME} and ${file:path} in config values
+var secretReference = regexp.MustCompile(`\$\{(env|file):([^}]+)\}`)
+
+// MergeSecretsFile decrypts a SOPS encrypted secrets file with the sops CLI and merges it into the config.
+// If path is empty, pocdeploy.secrets.yaml next to the config file is used if it exists.
+func MergeSecretsFile(path string) error {
+	if path == "" {
+		path = filepath.Join(configDir(), "pocdeploy.secrets.yaml")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("sops", "--decrypt", "--output-type", "yaml", path)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		err = fmt.Errorf("error decrypting %s with sops: %w: %s", path, err, strings.TrimSpace(stderr.String()))
+		return err
+	}
+
+	viper.SetConfigType("yaml")
+	if err := viper.MergeConfig(&stdout); err != nil {
+		err = fmt.Errorf("error merging %s into config: %w", path, err)
+		return err
+	}
+
+	return nil
+}
+
+// BindDeprecatedEnv keeps reading the unprefixed environment variables used before the POCDEPLOY_ prefix, such as
+// NAME or FRONTEND_TYPE, for every key in the config file or flags. The prefixed variable wins if both are set. It
+// returns the unprefixed variables that were found so they can be warned about.
+func BindDeprecatedEnv() ([]string, error) {
+	var found []string
+	for _, key := range viper.AllKeys() {
+		name := strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+		if err := viper.BindEnv(key, "POCDEPLOY_"+name, name); err != nil {
+			err = fmt.Errorf("error binding environment variables for %s: %w", key, err)
+			return nil, err
+		}
+		if _, ok := os.LookupEnv(name); ok {
+			found = append(found, name)
+		}
+	}
+
+	return found, nil
+}
+
+// ResolveSecretReferences replaces ${env:NAME} and ${file:path} in every config value, including values in lists and
+// lists of maps, with the environment variable or the file contents. Relative file paths are relative to the config
+// file. References are resolved in a copy of the settings that is merged back once, as setting a single nested key
+// would shadow its sibling keys.
+func ResolveSecretReferences() error {
+	settings := viper.AllSettings()
+	resolved, changed, err := resolveSettings("", settings)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+
+	if err = viper.MergeConfigMap(resolved.(map[string]any)); err != nil {
+		err = fmt.Errorf("error merging resolved config: %w", err)
+		return err
+	}
+
+	return nil
+}
+
+// resolveSettings resolves the references in value and everything nested in it, key is used in errors
+func resolveSettings(key string, value any) (any, bool, error) {
+	switch v := value.(type) {
+	case string:
+		resolved, err := resolveSecretReferences(v)
+		if err != nil {
+			err = fmt.Errorf("error resolving %s: %w", key, err)
+			return nil, false, err
+		}
+		return resolved, resolved != v, nil
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		changed := false
+		for k, item := range v {
+			resolved, c, err := resolveSettings(joinKey(key, k), item)
+			if err != nil {
+				return nil, false, err
+			}
+			out[k] = resolved
+			changed = changed || c
+		}
+		return out, changed, nil
+	case map[any]any:
+		out := make(map[string]any, len(v))
+		changed := false
+		for k, item := range v {
+			name := fmt.Sprint(k)
+			resolved, c, err := resolveSettings(joinKey(key, name), item)
+			if err != nil {
+				return nil, false, err
+			}
+			out[name] = resolved
+			changed = changed || c
+		}
+		return out, changed, nil
+	case []any:
+		out := make([]any, len(v))
+		changed := false
+		for i, item := range v {
+			resolved, c, err := resolveSettings(fmt.Sprintf("%s[%d]", key, i), item)
+			if err != nil {
+				return nil, false, err
+			}
+			out[i] = resolved
+			changed = changed || c
+		}
+		return out, changed, nil
+	}
+
+	return value, false, nil
+}
+
+// joinKey returns the dotted config key of name under parent
+func joinKey(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+
+	return parent + "." + name
+}
+
+// resolveSecretReferences replaces each reference in value
+func resolveSecretReferences(value string) (string, error) {
+	var err error
+
+	resolved := secretReference.ReplaceAllStringFunc(value, func(ref string) string {
+		match := secretReference.FindStringSubmatch(ref)
+		source, name := match[1], match[2]
+
+		switch source {
+		case "env":
+			v, ok := os.LookupEnv(name)
+			if !ok {
+				err = fmt.Errorf("environment variable %s is not set", name)
+			}
+			return v
+		case "file":
+			if !filepath.IsAbs(name) {
+				name = filepath.Join(configDir(), name)
+			}
+			content, readErr := os.ReadFile(name)
+			if readErr != nil {
+				err = fmt.Errorf("error reading %s: %w", name, readErr)
+			}
+			return strings.TrimRight(string(content), "\r\n")
+		}
+
+		return ref
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return resolved, nil
+}
+
+// configDir returns the directory of the config file in use, or the working directory if there is none
+func configDir() string {
+	if cfg := viper.ConfigFileUsed(); cfg != "" {
+		return filepath.Dir(cfg)
+	}
+
+	return "."
+}
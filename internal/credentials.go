@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/harvey-earth/pocdeploy/internal/models"
+)
+
+// GetCredentials reads the admin login and database connection string from the secrets in the cluster
+func GetCredentials() (models.Credentials, error) {
+	Debug("Reading credentials from cluster secrets")
+	creds := models.Credentials{}
+
+	clientset, err := kubernetesDefaultClient()
+	if err != nil {
+		err = fmt.Errorf("error creating client for credentials: %w", err)
+		return creds, err
+	}
+	secrets := clientset.CoreV1().Secrets("app")
+
+	// The admin secret only exists for frameworks that support admin creation
+	admin, err := secrets.Get(context.Background(), AdminSecretName, metav1.GetOptions{})
+	if err == nil {
+		creds.AdminUsername = string(admin.Data["username"])
+		creds.AdminEmail = string(admin.Data["email"])
+		creds.AdminPassword = string(admin.Data["password"])
+	} else if !errors.IsNotFound(err) {
+		err = fmt.Errorf("error reading %s secret: %w", AdminSecretName, err)
+		return creds, err
+	}
+
+	db, err := secrets.Get(context.Background(), databaseSecretName(), metav1.GetOptions{})
+	if err != nil {
+		err = fmt.Errorf("error reading %s secret: %w", databaseSecretName(), err)
+		return creds, err
+	}
+	port := string(db.Data["port"])
+	if port == "" {
+		port = "5432"
+	}
+	creds.DatabaseURL = databaseURL(db.Data, string(db.Data["host"])+":"+port)
+
+	Debug("Credentials read")
+	return creds, nil
+}
+
+// databaseURL returns a URL for host from the backend app secret data, using the scheme of the backend
+func databaseURL(data map[string][]byte, host string) string {
+	dbURL := url.URL{
+		Scheme: databaseScheme(),
+		User:   url.UserPassword(string(data["username"]), string(data["password"])),
+		Host:   host,
+		Path:   "/" + string(data["dbname"]),
+	}
+	if sslmode := databaseSSLMode(); sslmode != "" {
+		dbURL.RawQuery = "sslmode=" + sslmode
+	}
+
+	return dbURL.String()
+}
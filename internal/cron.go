@@ -0,0 +1,270 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/harvey-earth/pocdeploy/internal/models"
+)
+
+// CronPrefix is prepended to cron names for their CronJobs
+const CronPrefix = "cron-"
+
+// loadCron reads frontend.cron, setting defaults and validating each entry
+func loadCron() ([]models.Cron, error) {
+	var crons []models.Cron
+	if err := viper.UnmarshalKey("frontend.cron", &crons); err != nil {
+		err = fmt.Errorf("error reading frontend.cron: %w", err)
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for i := range crons {
+		c := &crons[i]
+		// CronJob names are limited to 52 characters so the job names generated from them fit
+		if errs := validation.IsDNS1123Label(CronPrefix + c.Name); c.Name == "" || len(errs) > 0 || len(CronPrefix+c.Name) > 52 {
+			return nil, fmt.Errorf("frontend.cron name %q must be a lowercase DNS label of at most %d characters", c.Name, 52-len(CronPrefix))
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("frontend.cron name %q is used more than once", c.Name)
+		}
+		names[c.Name] = true
+
+		if len(c.Command) == 0 {
+			return nil, fmt.Errorf("frontend.cron %s is missing command", c.Name)
+		}
+		if !strings.HasPrefix(c.Schedule, "@") && len(strings.Fields(c.Schedule)) != 5 {
+			return nil, fmt.Errorf("frontend.cron %s schedule %q must have 5 fields, ex. \"0 * * * *\"", c.Name, c.Schedule)
+		}
+		switch c.ConcurrencyPolicy {
+		case "":
+			c.ConcurrencyPolicy = string(batchv1.ForbidConcurrent)
+		case string(batchv1.AllowConcurrent), string(batchv1.ForbidConcurrent), string(batchv1.ReplaceConcurrent):
+		default:
+			return nil, fmt.Errorf("frontend.cron %s concurrency_policy must be Allow, Forbid or Replace, got %q", c.Name, c.ConcurrencyPolicy)
+		}
+		for _, limit := range []*int32{c.SuccessfulJobsHistoryLimit, c.FailedJobsHistoryLimit} {
+			if limit != nil && *limit < 0 {
+				return nil, fmt.Errorf("frontend.cron %s history limits can't be negative", c.Name)
+			}
+		}
+	}
+
+	return crons, nil
+}
+
+// CheckCronConfig validates frontend.cron so mistakes are caught before anything is created
+func CheckCronConfig() error {
+	_, err := loadCron()
+	return err
+}
+
+// cronJobs creates or updates a CronJob for each cron entry, and deletes the CronJobs of removed entries
+func cronJobs(clientset *kubernetes.Clientset, fw Framework, cfg appConfig) error {
+	crons, err := loadCron()
+	if err != nil {
+		return err
+	}
+	cronjobs := clientset.BatchV1().CronJobs(AppNamespace)
+
+	configured := map[string]bool{}
+	for _, c := range crons {
+		cronjob := cronJob(fw, cfg, c)
+		configured[cronjob.Name] = true
+
+		msg := fmt.Sprintf("Configuring cron %s", cronjob.Name)
+		Debug(msg)
+		_, err := cronjobs.Create(context.Background(), cronjob, metav1.CreateOptions{})
+		if errors.IsAlreadyExists(err) {
+			_, err = cronjobs.Update(context.Background(), cronjob, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			err = fmt.Errorf("error creating cron %s: %w", cronjob.Name, err)
+			return err
+		}
+	}
+
+	existing, err := cronjobs.List(context.Background(), metav1.ListOptions{
+		LabelSelector: "app.kubernetes.io/component=cron",
+	})
+	if err != nil {
+		err = fmt.Errorf("error listing cron jobs: %w", err)
+		return err
+	}
+	for _, cronjob := range existing.Items {
+		if configured[cronjob.Name] {
+			continue
+		}
+		msg := fmt.Sprintf("Deleting cron %s", cronjob.Name)
+		Debug(msg)
+		propagation := metav1.DeletePropagationBackground
+		err = cronjobs.Delete(context.Background(), cronjob.Name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+		if err != nil && !errors.IsNotFound(err) {
+			err = fmt.Errorf("error deleting cron %s: %w", cronjob.Name, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cronJob renders the CronJob of a cron entry from the shared pod template. Jobs connect to the primary directly, as
+// migrations do.
+func cronJob(fw Framework, cfg appConfig, c models.Cron) *batchv1.CronJob {
+	name := CronPrefix + c.Name
+	labels := map[string]string{
+		"app.kubernetes.io/component": "cron",
+		"app.kubernetes.io/name":      name,
+	}
+
+	template := podTemplate(fw, cfg, workload{
+		name:    name,
+		command: c.Command,
+	})
+	template.Labels["app.kubernetes.io/component"] = "cron"
+	template.Spec.RestartPolicy = corev1.RestartPolicyNever
+
+	return &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: AppNamespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:                   c.Schedule,
+			ConcurrencyPolicy:          batchv1.ConcurrencyPolicy(c.ConcurrencyPolicy),
+			SuccessfulJobsHistoryLimit: c.SuccessfulJobsHistoryLimit,
+			FailedJobsHistoryLimit:     c.FailedJobsHistoryLimit,
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: batchv1.JobSpec{
+					Template: template,
+				},
+			},
+		},
+	}
+}
+
+// RunCronJob starts a job from the CronJob of cron entry name, streams its output to w, and returns an error if the
+// job fails. The job is run once without retries so the output matches the result.
+func RunCronJob(name string, w io.Writer) error {
+	clientset, err := kubernetesDefaultClient()
+	if err != nil {
+		return err
+	}
+
+	cronjob, err := clientset.BatchV1().CronJobs(AppNamespace).Get(context.Background(), CronPrefix+name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return fmt.Errorf("no frontend.cron entry named %q has been created", name)
+	} else if err != nil {
+		err = fmt.Errorf("error getting cron %s: %w", name, err)
+		return err
+	}
+
+	var backoffLimit int32
+	isController := true
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%d", cronjob.Name, time.Now().Unix()),
+			Namespace: AppNamespace,
+			Labels:    cronjob.Spec.JobTemplate.Labels,
+			Annotations: map[string]string{
+				"cronjob.kubernetes.io/instantiate": "manual",
+			},
+			// The job is cleaned up with the CronJob
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: "batch/v1",
+					Kind:       "CronJob",
+					Name:       cronjob.Name,
+					UID:        cronjob.UID,
+					Controller: &isController,
+				},
+			},
+		},
+		Spec: cronjob.Spec.JobTemplate.Spec,
+	}
+	job.Spec.BackoffLimit = &backoffLimit
+
+	if _, err = clientset.BatchV1().Jobs(AppNamespace).Create(context.Background(), job, metav1.CreateOptions{}); err != nil {
+		err = fmt.Errorf("error creating job %s: %w", job.Name, err)
+		return err
+	}
+	msg := fmt.Sprintf("Started job %s", job.Name)
+	Info(msg)
+
+	if _, err = streamJobLogs(clientset, job.Name, w); err != nil {
+		return err
+	}
+
+	return waitForJob(clientset, job.Name)
+}
+
+// streamJobLogs waits for the pod of the named job to start and follows its logs to w until the container exits,
+// returning the pod name
+func streamJobLogs(clientset *kubernetes.Clientset, name string, w io.Writer) (string, error) {
+	pod, err := waitForJobPod(clientset, name)
+	if err != nil {
+		return "", err
+	}
+
+	stream, err := clientset.CoreV1().Pods(AppNamespace).GetLogs(pod, &corev1.PodLogOptions{
+		Follow: true,
+	}).Stream(context.Background())
+	if err != nil {
+		err = fmt.Errorf("error streaming logs of %s: %w", pod, err)
+		return pod, err
+	}
+	defer stream.Close()
+
+	if _, err = io.Copy(w, stream); err != nil {
+		err = fmt.Errorf("error streaming logs of %s: %w", pod, err)
+		return pod, err
+	}
+
+	return pod, nil
+}
+
+// waitForJobPod waits for a pod of the named job to leave the pending phase, so its logs can be read
+func waitForJobPod(clientset *kubernetes.Clientset, name string) (string, error) {
+	for i := 1; ; i++ {
+		pods, err := clientset.CoreV1().Pods(AppNamespace).List(context.Background(), metav1.ListOptions{
+			LabelSelector: "job-name=" + name,
+		})
+		if err != nil {
+			err = fmt.Errorf("error listing pods of %s job: %w", name, err)
+			return "", err
+		}
+		for _, pod := range pods.Items {
+			if pod.Status.Phase != corev1.PodPending {
+				return pod.Name, nil
+			}
+			// Surface image and config errors instead of waiting for the retries to run out
+			for _, status := range pod.Status.ContainerStatuses {
+				if waiting := status.State.Waiting; waiting != nil && waiting.Reason != "ContainerCreating" && waiting.Reason != "PodInitializing" {
+					return "", fmt.Errorf("pod %s of %s job can't start: %s: %s", pod.Name, name, waiting.Reason, waiting.Message)
+				}
+			}
+		}
+
+		msg := fmt.Sprintf("Waiting for %s job pod %d of %d", name, i, MaxRetries)
+		Debug(msg)
+		if i >= MaxRetries {
+			return "", fmt.Errorf("end of retries waiting for %s job pod", name)
+		}
+		time.Sleep(time.Duration(i*2) * time.Second)
+	}
+}
@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/harvey-earth/pocdeploy/internal/models"
+)
+
+// DatabaseShell opens an interactive psql session on the primary. The session uses the application role so created
+// objects are owned by it, unless superuser is set.
+func DatabaseShell(superuser bool) error {
+	if err := requireCNPG("db shell"); err != nil {
+		return err
+	}
+	clientset, err := kubernetesDefaultClient()
+	if err != nil {
+		return err
+	}
+	primary, err := primaryPod(clientset)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadBackendConfig()
+	if err != nil {
+		return err
+	}
+
+	command := []string{"psql", "-d", cfg.Database}
+	if !superuser {
+		command = append([]string{"env", "PGOPTIONS=-c role=" + cfg.Owner}, command...)
+	}
+
+	msg := fmt.Sprintf("Opening psql on %s", primary)
+	Debug(msg)
+	return execInPod(clientset, primary, "postgres", command, terminalStreams(true))
+}
+
+// DatabaseDump streams a pg_dump of the application database from the primary to w. format is passed to
+// pg_dump --format, ex. plain or custom. Nothing is logged so the dump can be redirected to a file.
+func DatabaseDump(w io.Writer, format string) error {
+	if err := requireCNPG("db dump"); err != nil {
+		return err
+	}
+	clientset, err := kubernetesDefaultClient()
+	if err != nil {
+		return err
+	}
+	primary, err := primaryPod(clientset)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadBackendConfig()
+	if err != nil {
+		return err
+	}
+
+	command := []string{"pg_dump", "--format=" + format, "--no-owner", "--no-privileges", "-d", cfg.Database}
+	return execInPod(clientset, primary, "postgres", command, streams{
+		stdout: w,
+		stderr: os.Stderr,
+	})
+}
+
+// ForwardDatabase forwards localPort to the primary behind the -rw service and prints the connection URL, returning
+// once interrupted
+func ForwardDatabase(localPort int) error {
+	if err := requireCNPG("db forward"); err != nil {
+		return err
+	}
+	clientset, err := kubernetesDefaultClient()
+	if err != nil {
+		return err
+	}
+	primary, err := primaryPod(clientset)
+	if err != nil {
+		return err
+	}
+	secret, err := clientset.CoreV1().Secrets(AppNamespace).Get(context.Background(), databaseSecretName(), metav1.GetOptions{})
+	if err != nil {
+		err = fmt.Errorf("error reading %s secret: %w", databaseSecretName(), err)
+		return err
+	}
+
+	stop := make(chan struct{})
+	ready := make(chan struct{})
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	go func() {
+		<-interrupt
+		close(stop)
+	}()
+	go func() {
+		<-ready
+		fmt.Printf("Forwarding localhost:%d to %s, press Ctrl-C to stop\n", localPort, primary)
+		fmt.Println("Database URL:", databaseURL(secret.Data, fmt.Sprintf("localhost:%d", localPort)))
+	}()
+
+	return portForward(clientset, primary, localPort, 5432, stop, ready)
+}
+
+// GetDatabaseInfo reads the state of the active cluster and its connection details
+func GetDatabaseInfo() (models.DatabaseInfo, error) {
+	if err := requireCNPG("db info"); err != nil {
+		return models.DatabaseInfo{}, err
+	}
+	info := models.DatabaseInfo{
+		Cluster: activeClusterName(),
+	}
+
+	clientdyn, err := kubernetesDynamicClient()
+	if err != nil {
+		return info, err
+	}
+	cluster, err := clientdyn.Resource(postgresGVR).Namespace(AppNamespace).Get(context.Background(), info.Cluster, metav1.GetOptions{})
+	if err != nil {
+		err = fmt.Errorf("error getting %s: %w", info.Cluster, err)
+		return info, err
+	}
+	info.Phase, _, _ = unstructured.NestedString(cluster.Object, "status", "phase")
+	info.Image, _, _ = unstructured.NestedString(cluster.Object, "status", "image")
+	info.Instances, _, _ = unstructured.NestedInt64(cluster.Object, "spec", "instances")
+	info.ReadyInstances, _, _ = unstructured.NestedInt64(cluster.Object, "status", "readyInstances")
+	info.Primary, _, _ = unstructured.NestedString(cluster.Object, "status", "currentPrimary")
+	info.ReadWrite = info.Cluster + "-rw"
+	info.ReadOnly = info.Cluster + "-ro"
+
+	clientset, err := kubernetesDefaultClient()
+	if err != nil {
+		return info, err
+	}
+	secret, err := clientset.CoreV1().Secrets(AppNamespace).Get(context.Background(), databaseSecretName(), metav1.GetOptions{})
+	if err != nil {
+		err = fmt.Errorf("error reading %s secret: %w", databaseSecretName(), err)
+		return info, err
+	}
+	info.Database = string(secret.Data["dbname"])
+	info.User = string(secret.Data["username"])
+	port := string(secret.Data["port"])
+	if port == "" {
+		port = "5432"
+	}
+	info.URL = databaseURL(secret.Data, string(secret.Data["host"])+":"+port)
+
+	return info, nil
+}
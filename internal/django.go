@@ -0,0 +1,179 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+
+	"github.com/harvey-earth/pocdeploy/internal/models"
+)
+
+// djangoFramework deploys Django apps
+type djangoFramework struct{}
+
+func init() {
+	registerFramework(djangoFramework{})
+}
+
+func (djangoFramework) Name() string {
+	return "django"
+}
+
+// Dockerfile returns an empty string as the Dockerfile is set with frontend.dockerfile
+func (djangoFramework) Dockerfile() string {
+	return ""
+}
+
+// PrepareBuildContext copies in requirements.txt if none exists and writes the server entrypoint
+func (djangoFramework) PrepareBuildContext(path string) error {
+	dst := filepath.Join(path, "requirements.txt")
+	if err := copyDeployFile("frontend/frontend-requirements.txt", dst); err != nil {
+		err = fmt.Errorf("error copying requirements.txt: %w", err)
+		return err
+	}
+
+	if err := writeDjangoEntrypoint(path); err != nil {
+		err = fmt.Errorf("error writing entrypoint: %w", err)
+		return err
+	}
+
+	return nil
+}
+
+func (djangoFramework) MigrateCommand() []string {
+	return []string{
+		"/env/bin/python",
+		"/app/manage.py",
+		"migrate",
+	}
+}
+
+// SeedCommand loads backend.seed.fixture with loaddata
+func (djangoFramework) SeedCommand(seed models.BackendSeed) []string {
+	if seed.Fixture == "" {
+		return nil
+	}
+
+	return []string{
+		"/env/bin/python",
+		"/app/manage.py",
+		"loaddata",
+		seed.Fixture,
+	}
+}
+
+// djangoAdminScript exits early if the superuser already exists, otherwise creates it from the DJANGO_SUPERUSER_* variables
+const djangoAdminScript = `/env/bin/python /app/manage.py shell --command "
+import os, sys
+from django.contrib.auth import get_user_model
+User = get_user_model()
+sys.exit(0 if User.objects.filter(**{User.USERNAME_FIELD: os.environ['DJANGO_SUPERUSER_USERNAME']}).exists() else 1)
+" && echo "Superuser already exists, skipping..." && exit 0
+exec /env/bin/python /app/manage.py createsuperuser --noinput`
+
+// AdminCommand creates the superuser with createsuperuser --noinput, which reads the credentials from the environment
+func (djangoFramework) AdminCommand() []string {
+	return []string{
+		"/bin/sh",
+		"-c",
+		djangoAdminScript,
+	}
+}
+
+func (djangoFramework) AdminEnv() []corev1.EnvVar {
+	return adminEnv("DJANGO_SUPERUSER_USERNAME", "DJANGO_SUPERUSER_EMAIL", "DJANGO_SUPERUSER_PASSWORD")
+}
+
+// IngressRules routes everything to the app, which serves the collectstatic output with whitenoise
+func (djangoFramework) IngressRules(service string, port int32) []networkingv1.HTTPIngressPath {
+	return prefixPaths(service, port, "/")
+}
+
+func (djangoFramework) IngressAnnotations() map[string]string {
+	return nil
+}
+
+func (djangoFramework) Probes(checkPath string, port int32) (*corev1.Probe, *corev1.Probe) {
+	return httpProbes(checkPath, "/", port)
+}
+
+// Resources returns no requests or limits
+func (djangoFramework) Resources() corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{}
+}
+
+func (djangoFramework) Port() int32 {
+	return FrontendPort
+}
+
+func (dj djangoFramework) Env() []corev1.EnvVar {
+	return append([]corev1.EnvVar{
+		{
+			Name:  "PORT",
+			Value: strconv.Itoa(int(frontendPort(dj))),
+		},
+		secretKeyEnv(),
+	}, webConcurrencyEnv()...)
+}
+
+// writeDjangoEntrypoint writes pocdeploy-entrypoint.sh to path, which starts the project with gunicorn,
+// using uvicorn workers when frontend.django.server is uvicorn
+func writeDjangoEntrypoint(path string) error {
+	module := viper.GetString("frontend.app_module")
+	if module == "" {
+		project, err := djangoProject(path)
+		if err != nil {
+			return err
+		}
+		module = project + ".wsgi:application"
+		if viper.GetString("frontend.django.server") == "uvicorn" {
+			module = project + ".asgi:application"
+		}
+	}
+
+	args := "--bind 0.0.0.0:${PORT:-8000} --workers ${WEB_CONCURRENCY:-2} --access-logfile -"
+	switch server := viper.GetString("frontend.django.server"); server {
+	case "", "gunicorn":
+	case "uvicorn":
+		args += " --worker-class uvicorn.workers.UvicornWorker"
+	default:
+		err := fmt.Errorf("unknown frontend.django.server %q, supported servers are: gunicorn, uvicorn", server)
+		return err
+	}
+
+	entrypoint := "#!/bin/sh\n" +
+		"# Generated by pocdeploy\n" +
+		"exec /env/bin/gunicorn " + module + " " + args + "\n"
+
+	dst := filepath.Join(path, "pocdeploy-entrypoint.sh")
+	if err := os.WriteFile(dst, []byte(entrypoint), 0o755); err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("Wrote %s serving %s", dst, module)
+	Debug(msg)
+	return nil
+}
+
+// djangoProject returns the project package from DJANGO_SETTINGS_MODULE in manage.py
+func djangoProject(path string) (string, error) {
+	manage, err := os.ReadFile(filepath.Join(path, "manage.py"))
+	if err != nil {
+		err = fmt.Errorf("error reading manage.py, set frontend.app_module instead: %w", err)
+		return "", err
+	}
+
+	match := regexp.MustCompile(`DJANGO_SETTINGS_MODULE['"],\s*['"]([\w.]+)\.settings['"]`).FindSubmatch(manage)
+	if match == nil {
+		err = fmt.Errorf("DJANGO_SETTINGS_MODULE not found in manage.py, set frontend.app_module instead")
+		return "", err
+	}
+
+	return string(match[1]), nil
+}
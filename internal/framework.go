@@ -0,0 +1,236 @@
+package internal
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/harvey-earth/pocdeploy/internal/models"
+)
+
+// Framework contains the steps that differ between frontend frameworks
+type Framework interface {
+	// Name returns the frontend.type value that selects the framework
+	Name() string
+	// Dockerfile returns the embedded Dockerfile used when frontend.dockerfile is unset, or an empty string if there is none
+	Dockerfile() string
+	// PrepareBuildContext adds any files the framework needs to the frontend code at path before the image is built
+	PrepareBuildContext(path string) error
+	// MigrateCommand returns the command run by the backend-init job
+	MigrateCommand() []string
+	// AdminCommand returns the command run by the create-admin job, or nil if admin creation is not supported
+	AdminCommand() []string
+	// AdminEnv returns the variables the create-admin job reads the admin credentials from
+	AdminEnv() []corev1.EnvVar
+	// IngressRules returns the ingress paths routed to the frontend service
+	IngressRules(service string, port int32) []networkingv1.HTTPIngressPath
+	// IngressAnnotations returns the annotations added to the frontend ingress
+	IngressAnnotations() map[string]string
+	// Probes returns the default liveness and readiness probes for the frontend container
+	Probes(checkPath string, port int32) (liveness *corev1.Probe, readiness *corev1.Probe)
+	// Resources returns the default resource requests and limits for the frontend container
+	Resources() corev1.ResourceRequirements
+	// Port returns the default port the frontend container listens on
+	Port() int32
+	// Env returns the environment variables the framework requires in addition to the database variables
+	Env() []corev1.EnvVar
+}
+
+// migrationRunner is implemented by frameworks whose migration job runs a dedicated image
+// with the SQL files from the migrations directory mounted at MigrationsMountPath
+type migrationRunner interface {
+	// MigrateImage returns the image the backend-init job runs
+	MigrateImage() string
+	// MigrationsDir returns the default migrations directory relative to frontend.path
+	MigrationsDir() string
+}
+
+// seeder is implemented by frameworks that load seed data with their own tooling
+type seeder interface {
+	// SeedCommand returns the command loading the fixture or task in seed, or nil if the framework doesn't use the
+	// field that is set
+	SeedCommand(seed models.BackendSeed) []string
+}
+
+// podCustomizer is implemented by frameworks that add containers or volumes to the frontend pod
+type podCustomizer interface {
+	// CustomizePod modifies the frontend pod spec before the deployment is created
+	CustomizePod(clientset *kubernetes.Clientset, spec *corev1.PodSpec, port int32) error
+}
+
+// MigrationsMountPath is where migration files are mounted in the backend-init job
+const MigrationsMountPath = "/migrations"
+
+// frameworks holds every registered framework by name
+var frameworks = map[string]Framework{}
+
+// registerFramework adds a framework to the registry, it is called from init in each framework file
+func registerFramework(fw Framework) {
+	frameworks[fw.Name()] = fw
+}
+
+// GetFramework returns the framework registered for frontend type t
+func GetFramework(t string) (Framework, error) {
+	fw, ok := frameworks[t]
+	if !ok {
+		err := fmt.Errorf("unknown frontend type %q, supported types are: %s", t, strings.Join(FrameworkNames(), ", "))
+		return nil, err
+	}
+
+	return fw, nil
+}
+
+// FrameworkNames returns the sorted names of all registered frameworks
+func FrameworkNames() []string {
+	names := make([]string, 0, len(frameworks))
+	for name := range frameworks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// frontendPort returns the port from frontend.port, or the framework default if unset
+func frontendPort(fw Framework) int32 {
+	if port := viper.GetInt32("frontend.port"); port != 0 {
+		return port
+	}
+
+	return fw.Port()
+}
+
+// migrateCommand returns the command from frontend.migrate_command, or the framework default if unset
+func migrateCommand(fw Framework) []string {
+	if command := viper.GetStringSlice("frontend.migrate_command"); len(command) > 0 {
+		return command
+	}
+
+	return fw.MigrateCommand()
+}
+
+// migrationsDir returns the directory from frontend.migrations_dir, or the framework default if unset,
+// relative to frontend.path
+func migrationsDir(runner migrationRunner) string {
+	dir := viper.GetString("frontend.migrations_dir")
+	if dir == "" {
+		dir = runner.MigrationsDir()
+	}
+
+	return filepath.Join(viper.GetString("frontend.path"), dir)
+}
+
+// httpProbes returns HTTP liveness and readiness probes for the given paths
+func httpProbes(livenessPath string, readinessPath string, port int32) (*corev1.Probe, *corev1.Probe) {
+	liveness := &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path: livenessPath,
+				Port: intstr.FromInt32(port),
+			},
+		},
+		InitialDelaySeconds: 15,
+		PeriodSeconds:       30,
+	}
+	readiness := &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path: readinessPath,
+				Port: intstr.FromInt32(port),
+			},
+		},
+		InitialDelaySeconds: 15,
+		PeriodSeconds:       30,
+	}
+
+	return liveness, readiness
+}
+
+// prefixPaths returns an ingress path routed to service for each prefix
+func prefixPaths(service string, port int32, prefixes ...string) []networkingv1.HTTPIngressPath {
+	pathType := networkingv1.PathTypePrefix
+	paths := []networkingv1.HTTPIngressPath{}
+
+	for _, prefix := range prefixes {
+		paths = append(paths, networkingv1.HTTPIngressPath{
+			Path:     prefix,
+			PathType: &pathType,
+			Backend: networkingv1.IngressBackend{
+				Service: &networkingv1.IngressServiceBackend{
+					Name: service,
+					Port: networkingv1.ServiceBackendPort{
+						Number: port,
+					},
+				},
+			},
+		})
+	}
+
+	return paths
+}
+
+// adminEnv returns variables with the given names set from the username, email and password in the admin credentials secret
+func adminEnv(username string, email string, password string) []corev1.EnvVar {
+	keys := []struct {
+		name string
+		key  string
+	}{
+		{username, "username"},
+		{email, "email"},
+		{password, "password"},
+	}
+
+	env := []corev1.EnvVar{}
+	for _, k := range keys {
+		env = append(env, corev1.EnvVar{
+			Name: k.name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: AdminSecretName,
+					},
+					Key: k.key,
+				},
+			},
+		})
+	}
+
+	return env
+}
+
+// secretKeyEnv returns the SECRET_KEY variable from the generated secret-key secret
+func secretKeyEnv() corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: "SECRET_KEY",
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: SecretKeySecretName,
+				},
+				Key: "key",
+			},
+		},
+	}
+}
+
+// webConcurrencyEnv returns WEB_CONCURRENCY from frontend.web_concurrency, which sets the server worker count
+func webConcurrencyEnv() []corev1.EnvVar {
+	workers := viper.GetString("frontend.web_concurrency")
+	if workers == "" {
+		return nil
+	}
+
+	return []corev1.EnvVar{
+		{
+			Name:  "WEB_CONCURRENCY",
+			Value: workers,
+		},
+	}
+}
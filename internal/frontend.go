@@ -15,6 +15,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes"
@@ -23,18 +24,35 @@ import (
 )
 
 // ConfigureFrontend creates a deployment, service, and ingress for the frontend built container
-func ConfigureFrontend() error {
+func ConfigureFrontend(fw Framework) error {
 	Info("Configuring frontend")
 
 	clientset, err := kubernetesDefaultClient()
 	if err != nil {
 		return err
 	}
-	if err = frontendDeployment(clientset); err != nil {
+	cfg, err := loadAppConfig()
+	if err != nil {
+		err = fmt.Errorf("error with frontend config: %w", err)
+		return err
+	}
+	if err = configFilesConfigMap(clientset, cfg); err != nil {
+		err = fmt.Errorf("error with frontend config files: %w", err)
+		return err
+	}
+	if err = frontendDeployment(clientset, fw, cfg); err != nil {
 		err = fmt.Errorf("error with frontend deployment: %w", err)
 		return err
 	}
-	if err = frontendService(clientset); err != nil {
+	if err = workerDeployments(clientset, fw, cfg); err != nil {
+		err = fmt.Errorf("error with frontend workers: %w", err)
+		return err
+	}
+	if err = cronJobs(clientset, fw, cfg); err != nil {
+		err = fmt.Errorf("error with frontend cron: %w", err)
+		return err
+	}
+	if err = frontendService(clientset, fw); err != nil {
 		err = fmt.Errorf("error with frontend service: %w", err)
 		return err
 	}
@@ -44,7 +62,7 @@ func ConfigureFrontend() error {
 			return err
 		}
 	}
-	if err = frontendIngress(clientset); err != nil {
+	if err = frontendIngress(clientset, fw); err != nil {
 		err = fmt.Errorf("error with frontend ingress: %w", err)
 		return err
 	}
@@ -53,14 +71,60 @@ func ConfigureFrontend() error {
 	return nil
 }
 
-// frontendDeployment creates the deployment
-func frontendDeployment(clientset *kubernetes.Clientset) error {
+// UpdateFrontend applies changes in the frontend env, config files, workers and cron to the running deployment. The
+// pod templates carry the config hash, so changes roll the pods.
+func UpdateFrontend(fw Framework) error {
+	Info("Updating frontend")
+
+	clientset, err := kubernetesDefaultClient()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadAppConfig()
+	if err != nil {
+		err = fmt.Errorf("error with frontend config: %w", err)
+		return err
+	}
+	if err = configFilesConfigMap(clientset, cfg); err != nil {
+		err = fmt.Errorf("error with frontend config files: %w", err)
+		return err
+	}
+	if err = frontendDeployment(clientset, fw, cfg); err != nil {
+		err = fmt.Errorf("error with frontend deployment: %w", err)
+		return err
+	}
+	if err = workerDeployments(clientset, fw, cfg); err != nil {
+		err = fmt.Errorf("error with frontend workers: %w", err)
+		return err
+	}
+	if err = cronJobs(clientset, fw, cfg); err != nil {
+		err = fmt.Errorf("error with frontend cron: %w", err)
+		return err
+	}
+
+	Info("Frontend updated")
+	return nil
+}
+
+// frontendDeployment creates the deployment, or updates it if it exists
+func frontendDeployment(clientset *kubernetes.Clientset, fw Framework, cfg appConfig) error {
 	Info("Creating frontend deployment")
 	name := viper.GetString("frontend.image")
 	vers := viper.GetString("frontend.version")
 	checkPath := viper.GetString("frontend.check_path")
-	imgStr := name + ":" + vers
 	reps := viper.GetInt32("frontend.size.min")
+	port := frontendPort(fw)
+
+	template := podTemplate(fw, cfg, workload{
+		name:     "frontend",
+		readOnly: viper.GetBool("frontend.database.read_only"),
+		pooled:   true,
+	})
+	// The service selects on the image name
+	template.Labels["app.kubernetes.io/name"] = name
+	container := &template.Spec.Containers[0]
+	container.LivenessProbe, container.ReadinessProbe = fw.Probes(checkPath, port)
+	container.Resources = fw.Resources()
 
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
@@ -79,104 +143,25 @@ func frontendDeployment(clientset *kubernetes.Clientset) error {
 					"app.kubernetes.io/name": name,
 				},
 			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
-						"app.kubernetes.io/name": name,
-					},
-				},
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{
-							Name:  "frontend",
-							Image: imgStr,
-							LivenessProbe: &corev1.Probe{
-								ProbeHandler: corev1.ProbeHandler{
-									HTTPGet: &corev1.HTTPGetAction{
-										Path: checkPath,
-										Port: intstr.FromInt(8000),
-									},
-								},
-								InitialDelaySeconds: 15,
-								PeriodSeconds:       30,
-							},
-							ReadinessProbe: &corev1.Probe{
-								ProbeHandler: corev1.ProbeHandler{
-									HTTPGet: &corev1.HTTPGetAction{
-										Path: "/",
-										Port: intstr.FromInt(8000),
-									},
-								},
-								InitialDelaySeconds: 15,
-								PeriodSeconds: 30,
-							},
-							Env: []corev1.EnvVar{
-								{
-									Name: "DATABASE_NAME",
-									ValueFrom: &corev1.EnvVarSource{
-										SecretKeyRef: &corev1.SecretKeySelector{
-											LocalObjectReference: corev1.LocalObjectReference{
-												Name: "poc-backend-cluster-app",
-											},
-											Key: "dbname",
-										},
-									},
-								},
-								{
-									Name: "DATABASE_USER",
-									ValueFrom: &corev1.EnvVarSource{
-										SecretKeyRef: &corev1.SecretKeySelector{
-											LocalObjectReference: corev1.LocalObjectReference{
-												Name: "poc-backend-cluster-app",
-											},
-											Key: "username",
-										},
-									},
-								},
-								{
-									Name: "DATABASE_PASSWORD",
-									ValueFrom: &corev1.EnvVarSource{
-										SecretKeyRef: &corev1.SecretKeySelector{
-											LocalObjectReference: corev1.LocalObjectReference{
-												Name: "poc-backend-cluster-app",
-											},
-											Key: "password",
-										},
-									},
-								},
-								{
-									Name: "DATABASE_HOST",
-									ValueFrom: &corev1.EnvVarSource{
-										SecretKeyRef: &corev1.SecretKeySelector{
-											LocalObjectReference: corev1.LocalObjectReference{
-												Name: "poc-backend-cluster-app",
-											},
-											Key: "host",
-										},
-									},
-								},
-								{
-									Name: "SECRET_KEY",
-									ValueFrom: &corev1.EnvVarSource{
-										SecretKeyRef: &corev1.SecretKeySelector{
-											LocalObjectReference: corev1.LocalObjectReference{
-												Name: "secret-key",
-											},
-											Key: "key",
-										},
-									},
-								},
-							},
-							ImagePullPolicy: corev1.PullNever,
-						},
-					},
-				},
-			},
+			Template: template,
 		},
 	}
 
+	// Let the framework add sidecars and volumes
+	if customizer, ok := fw.(podCustomizer); ok {
+		if err := customizer.CustomizePod(clientset, &deployment.Spec.Template.Spec, port); err != nil {
+			err = fmt.Errorf("error customizing %s frontend pod: %w", fw.Name(), err)
+			return err
+		}
+	}
+
+	deployments := clientset.AppsV1().Deployments("app")
 	for i := 1; ; i++ {
-		if _, err := clientset.AppsV1().Deployments("app").Create(context.Background(), deployment, metav1.CreateOptions{}); err != nil {
+		_, err := deployments.Create(context.Background(), deployment, metav1.CreateOptions{})
+		if errors.IsAlreadyExists(err) {
+			_, err = deployments.Update(context.Background(), deployment, metav1.UpdateOptions{})
+		}
+		if err != nil {
 			msg := fmt.Sprintf("Retrying frontend deployment %d of %d", i, MaxRetries)
 			Debug(msg)
 			time.Sleep(time.Duration(i*2) * time.Second)
@@ -194,10 +179,11 @@ func frontendDeployment(clientset *kubernetes.Clientset) error {
 }
 
 // frontendService creates the frontend-service
-func frontendService(clientset *kubernetes.Clientset) error {
+func frontendService(clientset *kubernetes.Clientset, fw Framework) error {
 	Info("Creating frontend service")
 	name := viper.GetString("frontend.image")
 	vers := viper.GetString("frontend.version")
+	port := frontendPort(fw)
 
 	service := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
@@ -215,8 +201,8 @@ func frontendService(clientset *kubernetes.Clientset) error {
 			},
 			Ports: []corev1.ServicePort{
 				{
-					Port:       8000,
-					TargetPort: intstr.FromInt32(8000),
+					Port:       port,
+					TargetPort: intstr.FromInt32(port),
 					NodePort:   30880,
 					Protocol:   corev1.ProtocolTCP,
 				},
@@ -243,32 +229,9 @@ func frontendService(clientset *kubernetes.Clientset) error {
 	return nil
 }
 
-func frontendIngress(clientset *kubernetes.Clientset) error {
+// frontendIngress creates the frontend ingress using the framework rules
+func frontendIngress(clientset *kubernetes.Clientset, fw Framework) error {
 	Info("Creating frontend ingress")
-	frontendType := viper.GetString("frontend.type")
-
-	switch frontendType {
-	case "django":
-		if err := frontendDjangoIngress(clientset); err != nil {
-			err = fmt.Errorf("error configuring django frontend ingress: %w", err)
-			return err
-		}
-	case "ror":
-		if err := frontendRorIngress(clientset); err != nil {
-			err = fmt.Errorf("error configuring RoR frontend ingress: %w", err)
-			return err
-		}
-	}
-
-	Info("Frontend ingress created")
-	return nil
-}
-
-// Creates the frontend ingress
-func frontendDjangoIngress(clientset *kubernetes.Clientset) error {
-	Debug("Configuring Django frontend ingress")
-
-	pathPtr := networkingv1.PathTypePrefix
 
 	ingress := &networkingv1.Ingress{
 		ObjectMeta: metav1.ObjectMeta{
@@ -278,46 +241,14 @@ func frontendDjangoIngress(clientset *kubernetes.Clientset) error {
 				"app.kubernetes.io/component": "ingress",
 				"app.kubernetes.io/name":      "ingress-nginx",
 			},
-			Annotations: map[string]string{
-				"nginx.ingress.kubernetes.io/configuration-snippet": `
-				location /static/ {
-					root /staticfiles;
-					expires 1y;
-					add_header Cache-Control "public, max-age=31536000, immutable";
-				}`,
-			},
+			Annotations: fw.IngressAnnotations(),
 		},
 		Spec: networkingv1.IngressSpec{
 			Rules: []networkingv1.IngressRule{
 				{
 					IngressRuleValue: networkingv1.IngressRuleValue{
 						HTTP: &networkingv1.HTTPIngressRuleValue{
-							Paths: []networkingv1.HTTPIngressPath{
-								{
-									Path:     "/static",
-									PathType: &pathPtr,
-									Backend: networkingv1.IngressBackend{
-										Service: &networkingv1.IngressServiceBackend{
-											Name: "frontend-service",
-											Port: networkingv1.ServiceBackendPort{
-												Number: 8000,
-											},
-										},
-									},
-								},
-								{
-									Path:     "/",
-									PathType: &pathPtr,
-									Backend: networkingv1.IngressBackend{
-										Service: &networkingv1.IngressServiceBackend{
-											Name: "frontend-service",
-											Port: networkingv1.ServiceBackendPort{
-												Number: 8000,
-											},
-										},
-									},
-								},
-							},
+							Paths: fw.IngressRules("frontend-service", frontendPort(fw)),
 						},
 					},
 				},
@@ -327,11 +258,11 @@ func frontendDjangoIngress(clientset *kubernetes.Clientset) error {
 
 	for i := 1; ; i++ {
 		if _, err := clientset.NetworkingV1().Ingresses("app").Create(context.Background(), ingress, metav1.CreateOptions{}); err != nil {
-			msg := fmt.Sprintf("Retrying django frontend ingress %d of %d", i, MaxRetries)
+			msg := fmt.Sprintf("Retrying %s frontend ingress %d of %d", fw.Name(), i, MaxRetries)
 			Debug(msg)
 			time.Sleep(time.Duration(i*2) * time.Second)
 			if i >= MaxRetries {
-				err = fmt.Errorf("end of retries for django frontend ingress: %w", err)
+				err = fmt.Errorf("end of retries for %s frontend ingress: %w", fw.Name(), err)
 				return err
 			}
 		} else {
@@ -339,66 +270,7 @@ func frontendDjangoIngress(clientset *kubernetes.Clientset) error {
 		}
 	}
 
-	Debug("Django frontend ingress configured")
-	return nil
-}
-
-// Creates the frontend ingress
-func frontendRorIngress(clientset *kubernetes.Clientset) error {
-	Debug("Configuring RoR frontend ingress")
-
-	pathPtr := networkingv1.PathTypePrefix
-
-	ingress := &networkingv1.Ingress{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "frontend-ingress",
-			Namespace: "app",
-			Labels: map[string]string{
-				"app.kubernetes.io/component": "ingress",
-				"app.kubernetes.io/name":      "ingress-nginx",
-			},
-		},
-		Spec: networkingv1.IngressSpec{
-			Rules: []networkingv1.IngressRule{
-				{
-					IngressRuleValue: networkingv1.IngressRuleValue{
-						HTTP: &networkingv1.HTTPIngressRuleValue{
-							Paths: []networkingv1.HTTPIngressPath{
-								{
-									Path:     "/",
-									PathType: &pathPtr,
-									Backend: networkingv1.IngressBackend{
-										Service: &networkingv1.IngressServiceBackend{
-											Name: "frontend-service",
-											Port: networkingv1.ServiceBackendPort{
-												Number: 8000,
-											},
-										},
-									},
-								},
-							},
-						},
-					},
-				},
-			},
-		},
-	}
-
-	for i := 1; ; i++ {
-		if _, err := clientset.NetworkingV1().Ingresses("app").Create(context.Background(), ingress, metav1.CreateOptions{}); err != nil {
-			msg := fmt.Sprintf("Retrying RoR frontend ingress %d of %d", i, MaxRetries)
-			Debug(msg)
-			time.Sleep(time.Duration(i*2) * time.Second)
-			if i >= MaxRetries {
-				err = fmt.Errorf("end of retries for RoR frontend ingress: %w", err)
-				return err
-			}
-		} else {
-			break
-		}
-	}
-
-	Debug("RoR frontend ingress configured")
+	Info("Frontend ingress created")
 	return nil
 }
 
@@ -446,7 +318,7 @@ func applyKindNginxIngress() error {
 	return nil
 }
 
-// CreateSecretKeySecret creates a secret key for the Django application
+// CreateSecretKeySecret creates a secret key for the frontend application, along with a Laravel APP_KEY
 func CreateSecretKeySecret() error {
 	randomString, err := generateSecretKey()
 	if err != nil {
@@ -454,6 +326,11 @@ func CreateSecretKeySecret() error {
 		return err
 	}
 	encodedString := base64.StdEncoding.EncodeToString([]byte(randomString))
+	appKey, err := generateAppKey()
+	if err != nil {
+		err = fmt.Errorf("error generating app key: %w", err)
+		return err
+	}
 
 	clientset, err := kubernetesDefaultClient()
 	if err != nil {
@@ -467,7 +344,8 @@ func CreateSecretKeySecret() error {
 			Namespace: "app",
 		},
 		Data: map[string][]byte{
-			"key": []byte(encodedString),
+			"key":     []byte(encodedString),
+			"app_key": []byte(appKey),
 		},
 		Type: v1.SecretTypeOpaque,
 	}
@@ -482,10 +360,15 @@ func CreateSecretKeySecret() error {
 
 // generateSecretKey generates a 50 character random string
 func generateSecretKey() (string, error) {
+	return generateRandomString(50)
+}
+
+// generateRandomString generates a random string of length characters using crypto/rand
+func generateRandomString(length int) (string, error) {
 	const characters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^&*()_-+=<>?/{}-|"
 	var result []byte
 
-	for i := 0; i < 50; i++ {
+	for i := 0; i < length; i++ {
 		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(characters))))
 		if err != nil {
 			return "", err
@@ -495,3 +378,13 @@ func generateSecretKey() (string, error) {
 
 	return string(result), nil
 }
+
+// generateAppKey generates a key in the base64:<32 random bytes> format Laravel expects for APP_KEY
+func generateAppKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	return "base64:" + base64.StdEncoding.EncodeToString(key), nil
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+// golangFramework deploys Go apps that migrate with golang-migrate
+type golangFramework struct{}
+
+func init() {
+	registerFramework(golangFramework{})
+}
+
+func (golangFramework) Name() string {
+	return "go"
+}
+
+func (golangFramework) Dockerfile() string {
+	return "frontend/go/Dockerfile"
+}
+
+// PrepareBuildContext does nothing as Go apps carry their own go.mod
+func (golangFramework) PrepareBuildContext(path string) error {
+	return nil
+}
+
+// MigrateCommand runs golang-migrate against the mounted migrations
+func (golangFramework) MigrateCommand() []string {
+	return []string{
+		"migrate",
+		"-path",
+		MigrationsMountPath,
+		"-database",
+		"$(DATABASE_URL)",
+		"up",
+	}
+}
+
+func (golangFramework) MigrateImage() string {
+	return "migrate/migrate:v4.17.1"
+}
+
+func (golangFramework) MigrationsDir() string {
+	return "migrations"
+}
+
+// AdminCommand returns nil as Go apps have no common admin user
+func (golangFramework) AdminCommand() []string {
+	return nil
+}
+
+func (golangFramework) AdminEnv() []corev1.EnvVar {
+	return nil
+}
+
+func (golangFramework) IngressRules(service string, port int32) []networkingv1.HTTPIngressPath {
+	return prefixPaths(service, port, "/")
+}
+
+func (golangFramework) IngressAnnotations() map[string]string {
+	return nil
+}
+
+// Probes checks the health check path for readiness as Go services often do not serve /
+func (golangFramework) Probes(checkPath string, port int32) (*corev1.Probe, *corev1.Probe) {
+	liveness, readiness := httpProbes(checkPath, checkPath, port)
+	liveness.InitialDelaySeconds = 5
+	readiness.InitialDelaySeconds = 2
+	readiness.PeriodSeconds = 10
+
+	return liveness, readiness
+}
+
+// Resources returns no requests or limits
+func (golangFramework) Resources() corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{}
+}
+
+func (golangFramework) Port() int32 {
+	return 8080
+}
+
+// Env sets PORT, DATABASE_URL is shared with every framework
+func (g golangFramework) Env() []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{
+			Name:  "PORT",
+			Value: strconv.Itoa(int(frontendPort(g))),
+		},
+		secretKeyEnv(),
+	}
+}
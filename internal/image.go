@@ -1,11 +1,14 @@
 package internal
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
 
@@ -13,18 +16,17 @@ import (
 )
 
 // BuildImage builds a docker image using the set variables from pocdeploy.yaml and returns a name and version of the built image
-func BuildImage() (name string, vers string, err error) {
+func BuildImage(fw Framework) (name string, vers string, err error) {
 	Info("Building docker image")
 
 	// Set variables
-	frontendType := viper.GetString("frontend.type")
 	path := viper.GetString("frontend.path")
 	patchDir := viper.GetString("frontend.patch_dir")
-	dockerfile := (viper.GetString("frontend.dockerfile"))
+	overlayDir := viper.GetString("frontend.overlay_dir")
+	strategy := viper.GetString("build.strategy")
 	image := viper.GetString("frontend.image")
 	vers = viper.GetString("frontend.version")
 	imgStr := image + ":" + vers
-	cmd := exec.Command("docker", "build", path, "-t", imgStr, "-f", dockerfile)
 
 	// Patch using build/patches
 	if err = cmdApplyPatches(path, patchDir); err != nil {
@@ -32,17 +34,28 @@ func BuildImage() (name string, vers string, err error) {
 		return "", "", err
 	}
 
-	if frontendType == "django" {
-		// Copy requirements.txt if none exists for Django
-		if err = copyRequirements(path); err != nil {
-			err = fmt.Errorf("error copying requirements.txt: %w", err)
-			return "", "", err
-		}
+	// Copy overlay files into the build context
+	if err = applyOverlays(path, overlayDir); err != nil {
+		err = fmt.Errorf("error applying overlays: %w", err)
+		return "", "", err
+	}
+
+	// Add framework specific files to the build context
+	if err = fw.PrepareBuildContext(path); err != nil {
+		err = fmt.Errorf("error preparing %s build context: %w", fw.Name(), err)
+		return "", "", err
 	}
 
 	// Build image
-	if err := cmd.Run(); err != nil {
-		err = fmt.Errorf("error building docker image: %w", err)
+	switch strategy {
+	case "", "dockerfile":
+		err = dockerBuild(fw, path, imgStr)
+	case "buildpacks":
+		err = buildpacksBuild(path, imgStr)
+	default:
+		err = fmt.Errorf("unknown build strategy %q, supported strategies are: dockerfile, buildpacks", strategy)
+	}
+	if err != nil {
 		return "", "", err
 	}
 
@@ -50,7 +63,95 @@ func BuildImage() (name string, vers string, err error) {
 	return image, vers, nil
 }
 
+// CheckBuildConfig validates the build settings for fw so mistakes are caught before anything is created. Buildpack
+// images don't have the paths the framework default migrate and admin commands use, such as Django's /env/bin/python.
+func CheckBuildConfig(fw Framework) error {
+	switch strategy := viper.GetString("build.strategy"); strategy {
+	case "", "dockerfile":
+		return nil
+	case "buildpacks":
+	default:
+		return fmt.Errorf("unknown build strategy %q, supported strategies are: dockerfile, buildpacks", strategy)
+	}
+
+	// Frameworks with a dedicated migration image don't run migrations from the buildpack image
+	if _, ok := fw.(migrationRunner); !ok && len(viper.GetStringSlice("frontend.migrate_command")) == 0 {
+		return fmt.Errorf("frontend.migrate_command is required for frontend type %s with build.strategy buildpacks", fw.Name())
+	}
+	if fw.Name() == "django" && viper.IsSet("frontend.admin") {
+		return fmt.Errorf("frontend.admin is not supported for frontend type django with build.strategy buildpacks")
+	}
+
+	return nil
+}
+
+// dockerBuild builds the image with frontend.dockerfile, or the framework's embedded Dockerfile if unset
+func dockerBuild(fw Framework, path string, imgStr string) error {
+	dockerfile := viper.GetString("frontend.dockerfile")
+
+	// Use the framework's embedded Dockerfile if none is set
+	if dockerfile == "" {
+		if fw.Dockerfile() == "" {
+			err := fmt.Errorf("frontend.dockerfile or build.strategy buildpacks is required for frontend type %s", fw.Name())
+			return err
+		}
+		content, err := d.DeployFiles.ReadFile(fw.Dockerfile())
+		if err != nil {
+			err = fmt.Errorf("error reading embedded Dockerfile: %w", err)
+			return err
+		}
+		tempfile, err := writeTempFile(content)
+		if err != nil {
+			err = fmt.Errorf("error writing Dockerfile tempfile: %w", err)
+			return err
+		}
+		defer os.Remove(tempfile.Name())
+		dockerfile = tempfile.Name()
+	}
+
+	cmd := exec.Command("docker", "build", path, "-t", imgStr, "-f", dockerfile)
+	if err := cmd.Run(); err != nil {
+		err = fmt.Errorf("error building docker image: %w", err)
+		return err
+	}
+
+	return nil
+}
+
+// buildpacksBuild builds the image from the frontend code with Cloud Native Buildpacks using the pack CLI.
+// Setting build.pull_policy to never uses only the builder and buildpacks in the local docker cache.
+func buildpacksBuild(path string, imgStr string) error {
+	builder := viper.GetString("build.builder")
+	if builder == "" {
+		builder = "paketobuildpacks/builder-jammy-base"
+	}
+	pullPolicy := viper.GetString("build.pull_policy")
+	if pullPolicy == "" {
+		pullPolicy = "if-not-present"
+	}
+
+	msg := fmt.Sprintf("Building %s with buildpacks using builder %s", imgStr, builder)
+	Debug(msg)
+
+	cmd := exec.Command("pack", "build", imgStr, "--path", path, "--builder", builder, "--pull-policy", pullPolicy)
+	for _, buildpack := range viper.GetStringSlice("build.buildpacks") {
+		cmd.Args = append(cmd.Args, "--buildpack", buildpack)
+	}
+	if err := cmd.Run(); err != nil {
+		err = fmt.Errorf("error building image with pack: %w", err)
+		return err
+	}
+
+	return nil
+}
+
+// cmdApplyPatches renders each patch file in patchDir as a template and applies it to repo with git
 func cmdApplyPatches(repo string, patchDir string) error {
+	if patchDir == "" {
+		Debug("No patch directory set, skipping...")
+		return nil
+	}
+
 	// Check if patchDir is empty
 	patchPath, err := filepath.Abs(patchDir)
 	if err != nil {
@@ -78,8 +179,24 @@ func cmdApplyPatches(repo string, patchDir string) error {
 	// Iterate through patch files
 	for _, f := range patchFiles {
 		filename := patchPath + "/" + f.Name()
-		cmd := exec.Command("git", "apply", filename)
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			err = fmt.Errorf("error reading patch file %s: %w", filename, err)
+			return err
+		}
+		// Only patches ending in .tmpl are templates, others may contain {{ for the app's own template languages
+		patch := content
+		if strings.HasSuffix(f.Name(), ".tmpl") {
+			if patch, err = renderTemplate(f.Name(), content); err != nil {
+				err = fmt.Errorf("error rendering patch %s: %w", filename, err)
+				return err
+			}
+		}
+
+		// git apply reads the rendered patch from stdin
+		cmd := exec.Command("git", "apply")
 		cmd.Dir = repoPath
+		cmd.Stdin = bytes.NewReader(patch)
 
 		if err := cmd.Run(); err != nil {
 			err = fmt.Errorf("error applying patch from %s: %w", filename, err)
@@ -91,9 +208,79 @@ func cmdApplyPatches(repo string, patchDir string) error {
 	return nil
 }
 
-func copyRequirements(dest string) error {
-	dst := filepath.Join(dest, "requirements.txt")
+// applyOverlays copies every file in overlayDir into repo at the same relative path.
+// Files ending in .tmpl are rendered as templates and written without the extension.
+func applyOverlays(repo string, overlayDir string) error {
+	if overlayDir == "" {
+		Debug("No overlay directory set, skipping...")
+		return nil
+	}
+
+	overlayPath, err := filepath.Abs(overlayDir)
+	if err != nil {
+		err = fmt.Errorf("error getting absolute path of overlay directory: %w", err)
+		return err
+	}
+	repoPath, err := filepath.Abs(repo)
+	if err != nil {
+		err = fmt.Errorf("error getting absolute path of frontend path: %w", err)
+		return err
+	}
+
+	msg := fmt.Sprintf("Applying overlays from %s to %s", overlayDir, repo)
+	Debug(msg)
+	err = filepath.WalkDir(overlayPath, func(src string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(overlayPath, src)
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(src)
+		if err != nil {
+			err = fmt.Errorf("error reading overlay file %s: %w", src, err)
+			return err
+		}
+		if strings.HasSuffix(rel, ".tmpl") {
+			rel = strings.TrimSuffix(rel, ".tmpl")
+			if content, err = renderTemplate(rel, content); err != nil {
+				return err
+			}
+		}
+
+		dst := filepath.Join(repoPath, rel)
+		if err = os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			err = fmt.Errorf("error creating directory for %s: %w", dst, err)
+			return err
+		}
+		if err = os.WriteFile(dst, content, info.Mode().Perm()); err != nil {
+			err = fmt.Errorf("error writing overlay file %s: %w", dst, err)
+			return err
+		}
+
+		msg := fmt.Sprintf("Copied overlay %s", rel)
+		Debug(msg)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	Debug("Applied overlays")
+	return nil
+}
 
+// copyDeployFile copies the embedded deploy file src to dst if dst does not already exist
+func copyDeployFile(src string, dst string) error {
 	if _, err := os.Stat(dst); err == nil {
 		// File exists, skip copying
 		msg := fmt.Sprintf("File %s already exists, skipping...\n", dst)
@@ -104,11 +291,12 @@ func copyRequirements(dest string) error {
 		return err
 	}
 
-	Debug("Proceeding to copy in frontend-requirements.txt")
+	msg := fmt.Sprintf("Proceeding to copy in %s", src)
+	Debug(msg)
 	// Open the source file
-	srcFile, err := d.DeployFiles.Open("frontend/frontend-requirements.txt")
+	srcFile, err := d.DeployFiles.Open(src)
 	if err != nil {
-		err = fmt.Errorf("error opening embeded frontend-requirements.txt file: %w", err)
+		err = fmt.Errorf("error opening embedded %s file: %w", src, err)
 		return err
 	}
 	defer srcFile.Close()
@@ -116,14 +304,14 @@ func copyRequirements(dest string) error {
 	// Create the destination file
 	dstFile, err := os.Create(dst)
 	if err != nil {
-		err = fmt.Errorf("error creating requirements.txt file: %w", err)
+		err = fmt.Errorf("error creating %s file: %w", dst, err)
 		return err
 	}
 	defer dstFile.Close()
 
 	// Copy the file contents from source to destination
 	if _, err := io.Copy(dstFile, srcFile); err != nil {
-		err = fmt.Errorf("error copying requirements.txt file: %w", err)
+		err = fmt.Errorf("error copying %s file: %w", dst, err)
 		return err
 	}
 
@@ -0,0 +1,231 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	d "github.com/harvey-earth/pocdeploy/deploy"
+)
+
+// laravelFramework deploys Laravel apps on php-fpm with an nginx sidecar
+type laravelFramework struct{}
+
+func init() {
+	registerFramework(laravelFramework{})
+}
+
+func (laravelFramework) Name() string {
+	return "laravel"
+}
+
+func (laravelFramework) Dockerfile() string {
+	return "frontend/laravel/Dockerfile"
+}
+
+// PrepareBuildContext does nothing as Laravel apps carry their own composer.json
+func (laravelFramework) PrepareBuildContext(path string) error {
+	return nil
+}
+
+func (laravelFramework) MigrateCommand() []string {
+	return []string{
+		"php",
+		"artisan",
+		"migrate",
+		"--force",
+	}
+}
+
+// AdminCommand returns nil as Laravel has no built in admin user
+func (laravelFramework) AdminCommand() []string {
+	return nil
+}
+
+func (laravelFramework) AdminEnv() []corev1.EnvVar {
+	return nil
+}
+
+func (laravelFramework) IngressRules(service string, port int32) []networkingv1.HTTPIngressPath {
+	return prefixPaths(service, port, "/")
+}
+
+func (laravelFramework) IngressAnnotations() map[string]string {
+	return nil
+}
+
+func (laravelFramework) Probes(checkPath string, port int32) (*corev1.Probe, *corev1.Probe) {
+	return httpProbes(checkPath, "/", port)
+}
+
+// Resources returns no requests or limits
+func (laravelFramework) Resources() corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{}
+}
+
+// Port returns the port the nginx sidecar listens on
+func (laravelFramework) Port() int32 {
+	return FrontendPort
+}
+
+// Env maps the database variables to the DB_* variables Laravel reads, and APP_KEY from the secret-key secret
+func (laravelFramework) Env() []corev1.EnvVar {
+	env := []corev1.EnvVar{
+		{
+			Name:  "DB_CONNECTION",
+			Value: "pgsql",
+		},
+		{
+			Name:  "DB_HOST",
+			Value: "$(DATABASE_HOST)",
+		},
+		{
+			Name:  "DB_PORT",
+			Value: "$(DATABASE_PORT)",
+		},
+		{
+			Name:  "DB_DATABASE",
+			Value: "$(DATABASE_NAME)",
+		},
+		{
+			Name:  "DB_USERNAME",
+			Value: "$(DATABASE_USER)",
+		},
+		{
+			Name:  "DB_PASSWORD",
+			Value: "$(DATABASE_PASSWORD)",
+		},
+		{
+			Name: "APP_KEY",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: SecretKeySecretName,
+					},
+					Key: "app_key",
+				},
+			},
+		},
+		{
+			Name:  "APP_ENV",
+			Value: "production",
+		},
+		{
+			Name:  "APP_DEBUG",
+			Value: "false",
+		},
+		{
+			Name:  "LOG_CHANNEL",
+			Value: "stderr",
+		},
+		secretKeyEnv(),
+	}
+	if sslmode := databaseSSLMode(); sslmode != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "DB_SSLMODE",
+			Value: "$(DATABASE_SSLMODE)",
+		})
+	}
+
+	return env
+}
+
+// CustomizePod adds the nginx sidecar, which serves the public directory copied out of the image by an init container
+// and passes PHP requests to php-fpm
+func (laravelFramework) CustomizePod(clientset *kubernetes.Clientset, spec *corev1.PodSpec, port int32) error {
+	Debug("Adding nginx sidecar to Laravel frontend")
+
+	content, err := d.DeployFiles.ReadFile("frontend/laravel/nginx.conf.tmpl")
+	if err != nil {
+		return err
+	}
+	nginxConf, err := renderTemplate("nginx.conf", content)
+	if err != nil {
+		return err
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "frontend-nginx",
+			Namespace: "app",
+			Labels: map[string]string{
+				"app.kubernetes.io/component": "configmap",
+				"app.kubernetes.io/name":      "frontend-nginx",
+			},
+		},
+		Data: map[string]string{
+			"default.conf": string(nginxConf),
+		},
+	}
+	configMaps := clientset.CoreV1().ConfigMaps("app")
+	_, err = configMaps.Create(context.Background(), configMap, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		_, err = configMaps.Update(context.Background(), configMap, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		err = fmt.Errorf("error creating frontend-nginx configmap: %w", err)
+		return err
+	}
+
+	spec.InitContainers = append(spec.InitContainers, corev1.Container{
+		Name:            "copy-public",
+		Image:           spec.Containers[0].Image,
+		Command:         []string{"cp", "-R", "/var/www/html/public/.", "/public/"},
+		ImagePullPolicy: corev1.PullNever,
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "public",
+				MountPath: "/public",
+			},
+		},
+	})
+	spec.Containers = append(spec.Containers, corev1.Container{
+		Name:  "nginx",
+		Image: "nginx:1.27-alpine",
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          "http",
+				ContainerPort: port,
+				Protocol:      corev1.ProtocolTCP,
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "nginx-conf",
+				MountPath: "/etc/nginx/conf.d",
+				ReadOnly:  true,
+			},
+			{
+				Name:      "public",
+				MountPath: "/var/www/html/public",
+				ReadOnly:  true,
+			},
+		},
+	})
+	spec.Volumes = append(spec.Volumes,
+		corev1.Volume{
+			Name: "nginx-conf",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: "frontend-nginx",
+					},
+				},
+			},
+		},
+		corev1.Volume{
+			Name: "public",
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		},
+	)
+
+	Debug("Nginx sidecar added")
+	return nil
+}
@@ -23,7 +23,7 @@ func InitLogger() error {
 		DisableCaller:     true,
 		Encoding:          "console",
 		OutputPaths: []string{
-			"stdout",
+			"stderr",
 		},
 		ErrorOutputPaths: []string{
 			"stderr",
@@ -0,0 +1,234 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+// logSource is a set of pods whose logs are shown for a component
+type logSource struct {
+	namespace string
+	selector  string
+}
+
+// LogComponents lists the components accepted by Logs
+var LogComponents = []string{"frontend", "backend", "init", "admin", "cnpg-operator", "prometheus"}
+
+// logSourceFor returns the pods of component
+func logSourceFor(component string) (logSource, error) {
+	switch component {
+	case "frontend":
+		return logSource{AppNamespace, "app.kubernetes.io/name=" + viper.GetString("frontend.image")}, nil
+	case "backend":
+		if backendType() == "mysql" {
+			return logSource{AppNamespace, "mysql.oracle.com/cluster=" + ClusterName}, nil
+		}
+		return logSource{AppNamespace, "cnpg.io/cluster=" + activeClusterName()}, nil
+	case "init":
+		return logSource{AppNamespace, "app.kubernetes.io/name=backend-init"}, nil
+	case "admin":
+		return logSource{AppNamespace, "app.kubernetes.io/name=create-admin"}, nil
+	case "cnpg-operator":
+		if err := requireCNPG("cnpg-operator logs"); err != nil {
+			return logSource{}, err
+		}
+		return logSource{"cnpg-system", "app.kubernetes.io/name=cloudnative-pg"}, nil
+	case "prometheus":
+		return logSource{AppNamespace, "prometheus=monitoring"}, nil
+	}
+
+	return logSource{}, fmt.Errorf("unknown component %q, supported components are: %s", component, strings.Join(LogComponents, ", "))
+}
+
+// logWriter writes lines from several containers to w without interleaving within a line
+type logWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// copyLines writes each line of r to the writer prefixed with prefix
+func (lw *logWriter) copyLines(prefix string, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	// Allow long lines, such as JSON logs
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lw.mu.Lock()
+		_, err := fmt.Fprintf(lw.w, "%s %s\n", prefix, scanner.Text())
+		lw.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Logs writes the logs of every container in the pods of components to w, prefixed with the pod and container
+// names. since limits the logs to that recent duration if it isn't zero. With follow set, logs are streamed from
+// existing and new pods until interrupted.
+func Logs(components []string, follow bool, since time.Duration, w io.Writer) error {
+	sources := []logSource{}
+	for _, component := range components {
+		source, err := logSourceFor(component)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, source)
+	}
+
+	clientset, err := kubernetesDefaultClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	options := corev1.PodLogOptions{
+		Follow: follow,
+	}
+	if since > 0 {
+		seconds := int64(since.Seconds())
+		options.SinceSeconds = &seconds
+	}
+
+	streamer := &logStreamer{
+		clientset: clientset,
+		options:   options,
+		out:       &logWriter{w: w},
+		started:   map[string]bool{},
+	}
+
+	for _, source := range sources {
+		pods, err := clientset.CoreV1().Pods(source.namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: source.selector,
+		})
+		if err != nil {
+			err = fmt.Errorf("error listing pods for %s: %w", source.selector, err)
+			return err
+		}
+		// Sorted so logs without --follow, which are streamed one container at a time, are in a stable order
+		sort.Slice(pods.Items, func(i, j int) bool {
+			return pods.Items[i].Name < pods.Items[j].Name
+		})
+		for i := range pods.Items {
+			streamer.start(ctx, &pods.Items[i])
+		}
+
+		if follow {
+			watcher, err := clientset.CoreV1().Pods(source.namespace).Watch(ctx, metav1.ListOptions{
+				LabelSelector:   source.selector,
+				ResourceVersion: pods.ResourceVersion,
+			})
+			if err != nil {
+				err = fmt.Errorf("error watching pods for %s: %w", source.selector, err)
+				return err
+			}
+			streamer.wg.Add(1)
+			go func() {
+				defer streamer.wg.Done()
+				defer watcher.Stop()
+				for event := range watcher.ResultChan() {
+					if pod, ok := event.Object.(*corev1.Pod); ok && (event.Type == watch.Added || event.Type == watch.Modified) {
+						streamer.start(ctx, pod)
+					}
+				}
+			}()
+		}
+	}
+
+	if follow {
+		<-ctx.Done()
+	}
+	streamer.wg.Wait()
+
+	return streamer.err
+}
+
+// logStreamer streams the logs of each container once
+type logStreamer struct {
+	clientset *kubernetes.Clientset
+	options   corev1.PodLogOptions
+	out       *logWriter
+	wg        sync.WaitGroup
+
+	mu      sync.Mutex
+	started map[string]bool
+	err     error
+}
+
+// start streams the logs of the init containers and containers of pod that have started and aren't streamed yet. A
+// restarted container is streamed again. When following they are streamed concurrently, otherwise one after another
+// before start returns.
+func (s *logStreamer) start(ctx context.Context, pod *corev1.Pod) {
+	statuses := append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if status.State.Waiting != nil && status.LastTerminationState.Terminated == nil {
+			// Nothing has been logged yet, a watch event follows once it starts
+			continue
+		}
+
+		key := fmt.Sprintf("%s/%s/%s/%d", pod.Namespace, pod.Name, status.Name, status.RestartCount)
+		s.mu.Lock()
+		if s.started[key] {
+			s.mu.Unlock()
+			continue
+		}
+		s.started[key] = true
+		s.mu.Unlock()
+
+		options := s.options
+		options.Container = status.Name
+		prefix := fmt.Sprintf("[%s/%s]", pod.Name, status.Name)
+		namespace, name := pod.Namespace, pod.Name
+
+		streamLogs := func() {
+			stream, err := s.clientset.CoreV1().Pods(namespace).GetLogs(name, &options).Stream(ctx)
+			if err == nil {
+				defer stream.Close()
+				err = s.out.copyLines(prefix, stream)
+			}
+			if err != nil && ctx.Err() == nil {
+				s.mu.Lock()
+				if s.err == nil {
+					s.err = fmt.Errorf("error streaming logs of %s: %w", prefix, err)
+				}
+				s.mu.Unlock()
+			}
+		}
+		if !s.options.Follow {
+			streamLogs()
+			continue
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			streamLogs()
+		}()
+	}
+}
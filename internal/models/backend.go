@@ -0,0 +1,65 @@
+package models
+
+// Backend represents the backend config section used to render the CloudNative PG Cluster
+type Backend struct {
+	Type            string            `mapstructure:"type"`
+	Instances       int               `mapstructure:"instances"`
+	Storage         BackendStorage    `mapstructure:"storage"`
+	WalStorage      BackendStorage    `mapstructure:"wal_storage"`
+	Image           string            `mapstructure:"image"`
+	PostgresVersion int               `mapstructure:"postgres_version"`
+	Parameters      map[string]string `mapstructure:"parameters"`
+	Resources       Resources         `mapstructure:"resources"`
+	Database        string            `mapstructure:"database"`
+	Owner           string            `mapstructure:"owner"`
+	Extensions      []string          `mapstructure:"extensions"`
+	Backup          BackendBackup     `mapstructure:"backup"`
+	Seed            BackendSeed       `mapstructure:"seed"`
+	Pooler          BackendPooler     `mapstructure:"pooler"`
+}
+
+// BackendStorage represents a volume of the Cluster, an empty Size means it is not configured
+type BackendStorage struct {
+	Size         string `mapstructure:"size"`
+	StorageClass string `mapstructure:"storage_class"`
+}
+
+// Resources represents the resource requests and limits of a container
+type Resources struct {
+	Requests map[string]string `mapstructure:"requests"`
+	Limits   map[string]string `mapstructure:"limits"`
+}
+
+// BackendBackup represents the backend.backup section. Backups are enabled if Destination is set or MinIO is true.
+type BackendBackup struct {
+	Destination     string `mapstructure:"destination"`
+	Endpoint        string `mapstructure:"endpoint"`
+	Region          string `mapstructure:"region"`
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key"`
+	Schedule        string `mapstructure:"schedule"`
+	Retention       string `mapstructure:"retention"`
+	MinIO           bool   `mapstructure:"minio"`
+	MinIOSize       string `mapstructure:"minio_size"`
+}
+
+// BackendSeed represents the backend.seed section, only one of the fields can be set
+type BackendSeed struct {
+	// File is a local .sql file loaded with psql or .dump file loaded with pg_restore
+	File string `mapstructure:"file"`
+	// Fixture is a Django fixture loaded with loaddata
+	Fixture string `mapstructure:"fixture"`
+	// Task is a Rails task such as db:seed
+	Task string `mapstructure:"task"`
+}
+
+// BackendPooler represents the backend.pooler section used to render the CloudNative PG Poolers
+type BackendPooler struct {
+	Enabled         bool   `mapstructure:"enabled"`
+	Instances       int    `mapstructure:"instances"`
+	PoolMode        string `mapstructure:"pool_mode"`
+	DefaultPoolSize int    `mapstructure:"default_pool_size"`
+	MaxClientConn   int    `mapstructure:"max_client_conn"`
+	// ReadOnly adds a pooler for the read only instances
+	ReadOnly bool `mapstructure:"read_only"`
+}
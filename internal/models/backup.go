@@ -0,0 +1,11 @@
+package models
+
+// Backup represents a CloudNative PG Backup of a backend cluster
+type Backup struct {
+	Name    string
+	Cluster string
+	Phase   string
+	Started string
+	Stopped string
+	Error   string
+}
@@ -0,0 +1,9 @@
+package models
+
+// Credentials represents the admin login and database connection details stored in the cluster
+type Credentials struct {
+	AdminUsername string
+	AdminEmail    string
+	AdminPassword string
+	DatabaseURL   string
+}
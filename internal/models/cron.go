@@ -0,0 +1,11 @@
+package models
+
+// Cron represents an entry of frontend.cron, a command from the frontend image run on a schedule
+type Cron struct {
+	Name                       string   `mapstructure:"name"`
+	Schedule                   string   `mapstructure:"schedule"`
+	Command                    []string `mapstructure:"command"`
+	ConcurrencyPolicy          string   `mapstructure:"concurrency_policy"`
+	SuccessfulJobsHistoryLimit *int32   `mapstructure:"successful_jobs_history_limit"`
+	FailedJobsHistoryLimit     *int32   `mapstructure:"failed_jobs_history_limit"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// DatabaseInfo represents the state of the active CloudNative PG cluster
+type DatabaseInfo struct {
+	Cluster        string
+	Phase          string
+	Image          string
+	Instances      int64
+	ReadyInstances int64
+	Primary        string
+	Database       string
+	User           string
+	ReadWrite      string
+	ReadOnly       string
+	URL            string
+}
@@ -0,0 +1,14 @@
+package models
+
+// SecretEnv represents a frontend.env_from_secret entry. If Name and Key are empty, every key in the secret is set.
+type SecretEnv struct {
+	Name   string `mapstructure:"name"`
+	Secret string `mapstructure:"secret"`
+	Key    string `mapstructure:"key"`
+}
+
+// ConfigFile represents a frontend.config_files entry, the local Source file is mounted at Mount
+type ConfigFile struct {
+	Source string `mapstructure:"source"`
+	Mount  string `mapstructure:"mount"`
+}
@@ -0,0 +1,22 @@
+package models
+
+// Services represents the services config section, supporting services deployed alongside the frontend
+type Services struct {
+	Redis    Redis    `mapstructure:"redis"`
+	RabbitMQ RabbitMQ `mapstructure:"rabbitmq"`
+}
+
+// Redis represents a single instance Redis used as a cache or queue
+type Redis struct {
+	Enabled   bool   `mapstructure:"enabled"`
+	Image     string `mapstructure:"image"`
+	Storage   string `mapstructure:"storage"`
+	MaxMemory string `mapstructure:"max_memory"`
+}
+
+// RabbitMQ represents a single instance RabbitMQ broker
+type RabbitMQ struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Image   string `mapstructure:"image"`
+	Storage string `mapstructure:"storage"`
+}
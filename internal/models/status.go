@@ -0,0 +1,9 @@
+package models
+
+// ComponentStatus represents the state of one deployed component shown by the status command
+type ComponentStatus struct {
+	Component string
+	Name      string
+	Ready     string
+	Status    string
+}
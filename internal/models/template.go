@@ -0,0 +1,32 @@
+package models
+
+// TemplateValues holds the pocdeploy configuration that patch and overlay templates are rendered against
+type TemplateValues struct {
+	Name      string
+	Namespace string
+	Host      string
+	CheckPath string
+	Port      int
+	Image     string
+	Version   string
+	Secrets   TemplateSecrets
+	Env       TemplateEnv
+}
+
+// TemplateSecrets holds the names of the secrets created in the cluster
+type TemplateSecrets struct {
+	Database  string
+	SecretKey string
+}
+
+// TemplateEnv holds the names of the environment variables set on the frontend containers
+type TemplateEnv struct {
+	DatabaseName     string
+	DatabaseUser     string
+	DatabasePassword string
+	DatabaseHost     string
+	DatabasePort     string
+	DatabaseURL      string
+	DatabaseEngine   string
+	SecretKey        string
+}
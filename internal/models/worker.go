@@ -0,0 +1,11 @@
+package models
+
+// Worker represents an entry of frontend.workers, a deployment running a background command from the frontend image
+type Worker struct {
+	Name            string    `mapstructure:"name"`
+	Command         []string  `mapstructure:"command"`
+	Replicas        int32     `mapstructure:"replicas"`
+	Resources       Resources `mapstructure:"resources"`
+	LivenessCommand []string  `mapstructure:"liveness_command"`
+	MetricsPort     int32     `mapstructure:"metrics_port"`
+}
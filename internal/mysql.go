@@ -0,0 +1,380 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+
+	d "github.com/harvey-earth/pocdeploy/deploy"
+	"github.com/harvey-earth/pocdeploy/internal/models"
+)
+
+// MySQLOperatorVersion sets the release of the embedded MySQL operator manifests
+const MySQLOperatorVersion = "9.1.0-2.2.2"
+
+// MySQLImage is the MySQL server image matching the operator release, also used for its client
+const MySQLImage = "container-registry.oracle.com/mysql/community-server:9.1.0"
+
+// MySQLRootSecretName is the secret holding the MySQL root credentials read by the operator
+const MySQLRootSecretName = "mysql-root"
+
+// innoDBClusterGVR is the MySQL operator InnoDBCluster resource
+var innoDBClusterGVR = schema.GroupVersionResource{
+	Group:    "mysql.oracle.com",
+	Version:  "v2",
+	Resource: "innodbclusters",
+}
+
+// mysqlBackend deploys a MySQL InnoDB Cluster with the MySQL operator
+type mysqlBackend struct{}
+
+func init() {
+	registerBackend(mysqlBackend{})
+}
+
+func (mysqlBackend) Name() string {
+	return "mysql"
+}
+
+func (mysqlBackend) Scheme() string {
+	return "mysql"
+}
+
+// Validate rejects the options that only apply to CloudNative PG
+func (mysqlBackend) Validate(cfg models.Backend) error {
+	unsupported := []struct {
+		key string
+		set bool
+	}{
+		{"backend.wal_storage", cfg.WalStorage.Size != ""},
+		{"backend.image", cfg.Image != ""},
+		{"backend.postgres_version", cfg.PostgresVersion != 0},
+		{"backend.parameters", len(cfg.Parameters) > 0},
+		{"backend.resources", len(cfg.Resources.Requests) > 0 || len(cfg.Resources.Limits) > 0},
+		{"backend.extensions", len(cfg.Extensions) > 0},
+		{"backend.backup", backupEnabled(cfg)},
+		{"backend.pooler", cfg.Pooler.Enabled},
+		{"backend.seed.file", cfg.Seed.File != ""},
+		{"frontend.database.sslmode", databaseSSLMode() != ""},
+		{"frontend.database.read_only", viper.GetBool("frontend.database.read_only")},
+	}
+	for _, option := range unsupported {
+		if option.set {
+			return fmt.Errorf("%s is not supported for the mysql backend", option.key)
+		}
+	}
+
+	return nil
+}
+
+// Status reads the status and online instances of the InnoDBCluster
+func (mysqlBackend) Status() (models.ComponentStatus, error) {
+	status := models.ComponentStatus{
+		Component: "backend",
+		Name:      ClusterName,
+	}
+
+	clientdyn, err := kubernetesDynamicClient()
+	if err != nil {
+		return status, err
+	}
+	cluster, err := clientdyn.Resource(innoDBClusterGVR).Namespace(AppNamespace).Get(context.Background(), ClusterName, metav1.GetOptions{})
+	if err != nil {
+		err = fmt.Errorf("error getting %s: %w", ClusterName, err)
+		return status, err
+	}
+	instances, _, _ := unstructured.NestedInt64(cluster.Object, "spec", "instances")
+	online, _, _ := unstructured.NestedInt64(cluster.Object, "status", "cluster", "onlineInstances")
+	status.Ready = fmt.Sprintf("%d/%d", online, instances)
+	status.Status, _, _ = unstructured.NestedString(cluster.Object, "status", "cluster", "status")
+
+	return status, nil
+}
+
+// Install applies the MySQL operator manifests vendored by make mysql-operator. The operator manifest creates a
+// ClusterKopfPeering, so the CRDs must be established before it is applied.
+func (mysqlBackend) Install() error {
+	Debug("Installing MySQL operator")
+
+	crds, err := applyMySQLOperatorManifest("mysql-operator-crds-" + MySQLOperatorVersion + ".yaml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(crds)
+
+	Debug("Waiting for MySQL operator CRDs to be established")
+	output, err := exec.Command("kubectl", "wait", "--for", "condition=established", "--timeout=120s", "-f", crds).CombinedOutput()
+	if err != nil {
+		err = fmt.Errorf("error waiting for MySQL operator CRDs with kubectl: %w: %s", err, strings.TrimSpace(string(output)))
+		return err
+	}
+
+	operator, err := applyMySQLOperatorManifest("mysql-operator-" + MySQLOperatorVersion + ".yaml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(operator)
+
+	Debug("MySQL operator installed")
+	return nil
+}
+
+// applyMySQLOperatorManifest applies an embedded MySQL operator manifest and returns the tempfile it was written to,
+// which the caller removes
+func applyMySQLOperatorManifest(manifest string) (string, error) {
+	content, err := d.DeployFiles.ReadFile("common/server/" + manifest)
+	if err != nil {
+		err = fmt.Errorf("error reading MySQL operator manifest %s, vendor it with make mysql-operator: %w", manifest, err)
+		return "", err
+	}
+
+	tempfile, err := writeTempFile(content)
+	if err != nil {
+		err = fmt.Errorf("error writing MySQL operator tempfile: %w", err)
+		return "", err
+	}
+
+	output, err := exec.Command("kubectl", "apply", "--server-side", "-f", tempfile.Name()).CombinedOutput()
+	if err != nil {
+		os.Remove(tempfile.Name())
+		err = fmt.Errorf("error installing MySQL operator %s with kubectl: %w: %s", manifest, err, strings.TrimSpace(string(output)))
+		return "", err
+	}
+
+	return tempfile.Name(), nil
+}
+
+// Configure creates the credentials secrets, the InnoDBCluster, and a job creating the application database and user,
+// which the operator doesn't manage
+func (mysqlBackend) Configure(cfg models.Backend) error {
+	Info("Configuring MySQL InnoDB Cluster")
+
+	clientset, err := kubernetesDefaultClient()
+	if err != nil {
+		return err
+	}
+	if err = mysqlSecrets(clientset, cfg); err != nil {
+		err = fmt.Errorf("error creating MySQL secrets: %w", err)
+		return err
+	}
+
+	clientdyn, err := kubernetesDynamicClient()
+	if err != nil {
+		return err
+	}
+
+	datadir := map[string]any{
+		"accessModes": []any{"ReadWriteOnce"},
+		"resources": map[string]any{
+			"requests": map[string]any{
+				"storage": cfg.Storage.Size,
+			},
+		},
+	}
+	if cfg.Storage.StorageClass != "" {
+		datadir["storageClassName"] = cfg.Storage.StorageClass
+	}
+
+	cluster := &unstructured.Unstructured{
+		Object: map[string]any{
+			"apiVersion": "mysql.oracle.com/v2",
+			"kind":       "InnoDBCluster",
+			"metadata": map[string]any{
+				"name":      ClusterName,
+				"namespace": AppNamespace,
+				"labels": map[string]any{
+					"app.kubernetes.io/component": "cluster",
+					"app.kubernetes.io/name":      "backend",
+				},
+			},
+			"spec": map[string]any{
+				"secretName":       MySQLRootSecretName,
+				"tlsUseSelfSigned": true,
+				"instances":        int64(cfg.Instances),
+				"router": map[string]any{
+					"instances": int64(1),
+				},
+				"datadirVolumeClaimTemplate": datadir,
+			},
+		},
+	}
+
+	for i := 1; ; i++ {
+		if _, err := clientdyn.Resource(innoDBClusterGVR).Namespace(AppNamespace).Create(context.Background(), cluster, metav1.CreateOptions{}); err != nil {
+			msg := fmt.Sprintf("Retrying backend configuration %d of %d", i, MaxRetries)
+			Debug(msg)
+			time.Sleep(time.Duration(i*2) * time.Second)
+			if i >= MaxRetries {
+				err = fmt.Errorf("reached end of retries for backend configuration: %w", err)
+				return err
+			}
+		} else {
+			break
+		}
+	}
+
+	if err = mysqlAppUser(clientset); err != nil {
+		err = fmt.Errorf("error creating MySQL application user: %w", err)
+		return err
+	}
+
+	Info("MySQL InnoDB Cluster configured")
+	return nil
+}
+
+// Update scales the InnoDBCluster, the only setting the operator can change on a running cluster
+func (mysqlBackend) Update(cfg models.Backend) error {
+	Info("Updating MySQL InnoDB Cluster")
+
+	clientdyn, err := kubernetesDynamicClient()
+	if err != nil {
+		return err
+	}
+	clusters := clientdyn.Resource(innoDBClusterGVR).Namespace(AppNamespace)
+
+	cluster, err := clusters.Get(context.Background(), ClusterName, metav1.GetOptions{})
+	if err != nil {
+		err = fmt.Errorf("error getting %s: %w", ClusterName, err)
+		return err
+	}
+	if err = unstructured.SetNestedField(cluster.Object, int64(cfg.Instances), "spec", "instances"); err != nil {
+		return err
+	}
+	if _, err = clusters.Update(context.Background(), cluster, metav1.UpdateOptions{}); err != nil {
+		err = fmt.Errorf("error updating %s: %w", ClusterName, err)
+		return err
+	}
+
+	Info("MySQL InnoDB Cluster updated")
+	return nil
+}
+
+// mysqlSecrets creates the root secret read by the operator and the application secret read by the frontend, with
+// the same keys CloudNative PG uses. Passwords are generated once and reused.
+func mysqlSecrets(clientset *kubernetes.Clientset, cfg models.Backend) error {
+	rootPassword, err := storedPassword(clientset, MySQLRootSecretName, "rootPassword")
+	if err != nil {
+		return err
+	}
+	appPassword, err := storedPassword(clientset, databaseSecretName(), "password")
+	if err != nil {
+		return err
+	}
+
+	secrets := []*corev1.Secret{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      MySQLRootSecretName,
+				Namespace: AppNamespace,
+				Labels: map[string]string{
+					"app.kubernetes.io/component": "secret",
+					"app.kubernetes.io/name":      "backend",
+				},
+			},
+			StringData: map[string]string{
+				"rootUser":     "root",
+				"rootHost":     "%",
+				"rootPassword": rootPassword,
+			},
+			Type: corev1.SecretTypeOpaque,
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      databaseSecretName(),
+				Namespace: AppNamespace,
+				Labels: map[string]string{
+					"app.kubernetes.io/component": "secret",
+					"app.kubernetes.io/name":      "backend",
+				},
+			},
+			StringData: map[string]string{
+				"dbname":   cfg.Database,
+				"username": cfg.Owner,
+				"password": appPassword,
+				// The router service sends connections to the primary
+				"host": ClusterName,
+				"port": "3306",
+			},
+			Type: corev1.SecretTypeOpaque,
+		},
+	}
+
+	for _, secret := range secrets {
+		_, err := clientset.CoreV1().Secrets(AppNamespace).Create(context.Background(), secret, metav1.CreateOptions{})
+		if errors.IsAlreadyExists(err) {
+			_, err = clientset.CoreV1().Secrets(AppNamespace).Update(context.Background(), secret, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			err = fmt.Errorf("error creating %s secret: %w", secret.Name, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mysqlAppUserScript creates the application database and user from the secrets, the names are validated identifiers
+const mysqlAppUserScript = `mysql -h "$DATABASE_HOST" -P "$DATABASE_PORT" -u root -p"$ROOT_PASSWORD" -e "
+CREATE DATABASE IF NOT EXISTS $DATABASE_NAME;
+CREATE USER IF NOT EXISTS '$DATABASE_USER'@'%' IDENTIFIED BY '$DATABASE_PASSWORD';
+ALTER USER '$DATABASE_USER'@'%' IDENTIFIED BY '$DATABASE_PASSWORD';
+GRANT ALL PRIVILEGES ON $DATABASE_NAME.* TO '$DATABASE_USER'@'%';"`
+
+// mysqlAppUser runs a job creating the application database and user, retrying until the cluster is ready
+func mysqlAppUser(clientset *kubernetes.Clientset) error {
+	var backoffLimit int32 = 10
+
+	env := databaseEnv(false, false)
+	env = append(env, corev1.EnvVar{
+		Name: "ROOT_PASSWORD",
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: MySQLRootSecretName,
+				},
+				Key: "rootPassword",
+			},
+		},
+	})
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "mysql-app-user",
+			Namespace: AppNamespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/component": "job",
+				"app.kubernetes.io/name":      "mysql-app-user",
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:    "mysql-app-user",
+							Image:   MySQLImage,
+							Command: []string{"/bin/sh", "-c", mysqlAppUserScript},
+							Env:     env,
+						},
+					},
+					RestartPolicy: corev1.RestartPolicyOnFailure,
+				},
+			},
+		},
+	}
+
+	return recreateJob(clientset, job)
+}
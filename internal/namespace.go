@@ -3,8 +3,10 @@ package internal
 import (
 	"context"
 	"fmt"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -31,3 +33,41 @@ func CreateNamespaces() error {
 	Info("Namespace created")
 	return nil
 }
+
+// DeleteAppNamespace deletes the app namespace and waits for it to be removed, which deletes the frontend, the backend
+// cluster, the services and their volumes
+func DeleteAppNamespace() error {
+	Info("Deleting namespace")
+
+	clientset, err := kubernetesDefaultClient()
+	if err != nil {
+		err = fmt.Errorf("error creating client for namespaces: %w", err)
+		return err
+	}
+
+	err = clientset.CoreV1().Namespaces().Delete(context.Background(), AppNamespace, metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		err = fmt.Errorf("error deleting namespace %s: %w", AppNamespace, err)
+		return err
+	}
+
+	// Operators remove their finalizers before the namespace is gone
+	for i := 1; ; i++ {
+		_, err := clientset.CoreV1().Namespaces().Get(context.Background(), AppNamespace, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			break
+		}
+		msg := fmt.Sprintf("Waiting for namespace deletion %d of %d", i, MaxRetries)
+		Debug(msg)
+		time.Sleep(time.Duration(i*2) * time.Second)
+		if i >= MaxRetries {
+			err = fmt.Errorf("reached end of retries waiting for namespace %s deletion", AppNamespace)
+			return err
+		}
+	}
+
+	Info("Namespace deleted")
+	return nil
+}
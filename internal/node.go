@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+// nodeFramework deploys Node.js apps such as Express or Next.js using Prisma or Knex
+type nodeFramework struct{}
+
+func init() {
+	registerFramework(nodeFramework{})
+}
+
+func (nodeFramework) Name() string {
+	return "node"
+}
+
+func (nodeFramework) Dockerfile() string {
+	return "frontend/node/Dockerfile"
+}
+
+// PrepareBuildContext does nothing as Node apps carry their own package.json
+func (nodeFramework) PrepareBuildContext(path string) error {
+	return nil
+}
+
+// MigrateCommand runs Prisma migrations, Knex apps set frontend.migrate_command to ["npx", "knex", "migrate:latest"]
+func (nodeFramework) MigrateCommand() []string {
+	return []string{
+		"npx",
+		"prisma",
+		"migrate",
+		"deploy",
+	}
+}
+
+// AdminCommand returns nil as Node apps have no common admin user
+func (nodeFramework) AdminCommand() []string {
+	return nil
+}
+
+func (nodeFramework) AdminEnv() []corev1.EnvVar {
+	return nil
+}
+
+func (nodeFramework) IngressRules(service string, port int32) []networkingv1.HTTPIngressPath {
+	return prefixPaths(service, port, "/")
+}
+
+func (nodeFramework) IngressAnnotations() map[string]string {
+	return nil
+}
+
+// Probes checks the health check path for readiness as Node APIs often do not serve /
+func (nodeFramework) Probes(checkPath string, port int32) (*corev1.Probe, *corev1.Probe) {
+	liveness, readiness := httpProbes(checkPath, checkPath, port)
+	readiness.InitialDelaySeconds = 5
+	readiness.PeriodSeconds = 10
+
+	return liveness, readiness
+}
+
+// Resources returns no requests or limits
+func (nodeFramework) Resources() corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{}
+}
+
+func (nodeFramework) Port() int32 {
+	return 3000
+}
+
+// Env sets PORT, DATABASE_URL is shared with every framework
+func (n nodeFramework) Env() []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{
+			Name:  "PORT",
+			Value: strconv.Itoa(int(frontendPort(n))),
+		},
+		secretKeyEnv(),
+	}
+}
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/harvey-earth/pocdeploy/internal/models"
+)
+
+// poolerGVR is the CloudNative PG Pooler resource
+var poolerGVR = schema.GroupVersionResource{
+	Group:    "postgresql.cnpg.io",
+	Version:  "v1",
+	Resource: "poolers",
+}
+
+// poolerName returns the name of the pooler and its service for cluster, poolerType is rw or ro
+func poolerName(cluster, poolerType string) string {
+	return cluster + "-pooler-" + poolerType
+}
+
+// poolerEnabled returns true if backend.pooler.enabled is set
+func poolerEnabled() bool {
+	return viper.GetBool("backend.pooler.enabled")
+}
+
+// readOnlyPoolerEnabled returns true if a pooler is also created for the read only instances
+func readOnlyPoolerEnabled() bool {
+	return poolerEnabled() && viper.GetBool("backend.pooler.read_only")
+}
+
+// configurePoolers creates or updates the rw and ro poolers of cluster, deleting any that are disabled
+func configurePoolers(cfg models.Backend, cluster string) error {
+	clientdyn, err := kubernetesDynamicClient()
+	if err != nil {
+		return err
+	}
+	poolers := clientdyn.Resource(poolerGVR).Namespace(AppNamespace)
+
+	for _, poolerType := range []string{"rw", "ro"} {
+		name := poolerName(cluster, poolerType)
+		enabled := cfg.Pooler.Enabled && (poolerType == "rw" || cfg.Pooler.ReadOnly)
+		if !enabled {
+			err = poolers.Delete(context.Background(), name, metav1.DeleteOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				err = fmt.Errorf("error deleting pooler %s: %w", name, err)
+				return err
+			}
+			continue
+		}
+
+		msg := fmt.Sprintf("Configuring pooler %s", name)
+		Debug(msg)
+		spec := poolerSpec(cfg.Pooler, cluster, poolerType)
+
+		existing, err := poolers.Get(context.Background(), name, metav1.GetOptions{})
+		if err == nil {
+			if err = unstructured.SetNestedMap(existing.Object, spec, "spec"); err != nil {
+				return err
+			}
+			if _, err = poolers.Update(context.Background(), existing, metav1.UpdateOptions{}); err != nil {
+				err = fmt.Errorf("error updating pooler %s: %w", name, err)
+				return err
+			}
+			continue
+		} else if !errors.IsNotFound(err) {
+			return err
+		}
+
+		pooler := &unstructured.Unstructured{
+			Object: map[string]any{
+				"apiVersion": "postgresql.cnpg.io/v1",
+				"kind":       "Pooler",
+				"metadata": map[string]any{
+					"name":      name,
+					"namespace": AppNamespace,
+					"labels": map[string]any{
+						"app.kubernetes.io/component": "pooler",
+						"app.kubernetes.io/name":      "backend",
+					},
+				},
+				"spec": spec,
+			},
+		}
+		if _, err = poolers.Create(context.Background(), pooler, metav1.CreateOptions{}); err != nil {
+			err = fmt.Errorf("error creating pooler %s: %w", name, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// poolerSpec renders the spec of a PgBouncer pooler for cluster
+func poolerSpec(pooler models.BackendPooler, cluster, poolerType string) map[string]any {
+	parameters := map[string]any{}
+	if pooler.DefaultPoolSize > 0 {
+		parameters["default_pool_size"] = strconv.Itoa(pooler.DefaultPoolSize)
+	}
+	if pooler.MaxClientConn > 0 {
+		parameters["max_client_conn"] = strconv.Itoa(pooler.MaxClientConn)
+	}
+
+	return map[string]any{
+		"cluster": map[string]any{
+			"name": cluster,
+		},
+		"instances": int64(pooler.Instances),
+		"type":      poolerType,
+		"pgbouncer": map[string]any{
+			"poolMode":   pooler.PoolMode,
+			"parameters": parameters,
+		},
+	}
+}
+
+// deletePoolers deletes the poolers of cluster, which are not removed with it
+func deletePoolers(cluster string) error {
+	clientdyn, err := kubernetesDynamicClient()
+	if err != nil {
+		return err
+	}
+	poolers := clientdyn.Resource(poolerGVR).Namespace(AppNamespace)
+
+	for _, poolerType := range []string{"rw", "ro"} {
+		err = poolers.Delete(context.Background(), poolerName(cluster, poolerType), metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
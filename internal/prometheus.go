@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -125,7 +126,7 @@ func configurePrometheus(clientset *dynamic.DynamicClient) error {
 		}
 	}
 
-	// Install PodMonitor
+	// Install PodMonitors
 	for i := 15; ; i++ {
 		if _, err := clientset.Resource(podGVR).Namespace("app").Create(context.Background(), podMonitor, metav1.CreateOptions{}); err != nil {
 			msg := fmt.Sprintf("Retrying prometheus podmonitor configuration %d of %d", i, MaxRetries)
@@ -139,6 +140,12 @@ func configurePrometheus(clientset *dynamic.DynamicClient) error {
 			break
 		}
 	}
+	for name, component := range map[string]string{"services": "service", "workers": "worker"} {
+		if _, err := clientset.Resource(podGVR).Namespace("app").Create(context.Background(), componentPodMonitor(name, component), metav1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+			err = fmt.Errorf("error creating %s podmonitor: %w", name, err)
+			return err
+		}
+	}
 
 	Debug("Prometheus PodMonitor configured")
 	return nil
@@ -168,3 +175,34 @@ func installPrometheus(vers string) error {
 	Debug("Prometheus Operator installed")
 	return nil
 }
+
+// componentPodMonitor scrapes the metrics port of pods with the component label, the Redis exporter and RabbitMQ for
+// services and workers with metrics_port set
+func componentPodMonitor(name, component string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]any{
+			"apiVersion": "monitoring.coreos.com/v1",
+			"kind":       "PodMonitor",
+			"metadata": map[string]any{
+				"name":      name,
+				"namespace": "app",
+				"labels": map[string]any{
+					"app.kubernetes.io/component": "podmonitor",
+					"app.kubernetes.io/name":      "prometheus",
+				},
+			},
+			"spec": map[string]any{
+				"selector": map[string]any{
+					"matchLabels": map[string]any{
+						"app.kubernetes.io/component": component,
+					},
+				},
+				"podMetricsEndpoints": []any{
+					map[string]any{
+						"port": "metrics",
+					},
+				},
+			},
+		},
+	}
+}
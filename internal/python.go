@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"strconv"
+
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+// alembicFramework deploys Python WSGI and ASGI apps, such as Flask and FastAPI, that migrate with Alembic
+type alembicFramework struct {
+	name       string
+	dockerfile string
+}
+
+func init() {
+	registerFramework(alembicFramework{name: "flask", dockerfile: "frontend/flask/Dockerfile"})
+	registerFramework(alembicFramework{name: "fastapi", dockerfile: "frontend/fastapi/Dockerfile"})
+}
+
+func (a alembicFramework) Name() string {
+	return a.name
+}
+
+func (a alembicFramework) Dockerfile() string {
+	return a.dockerfile
+}
+
+// PrepareBuildContext does nothing as the embedded Dockerfile installs the server, driver and Alembic
+func (alembicFramework) PrepareBuildContext(path string) error {
+	return nil
+}
+
+func (alembicFramework) MigrateCommand() []string {
+	return []string{
+		"alembic",
+		"upgrade",
+		"head",
+	}
+}
+
+// AdminCommand returns nil as Flask and FastAPI have no built in admin user
+func (alembicFramework) AdminCommand() []string {
+	return nil
+}
+
+func (alembicFramework) AdminEnv() []corev1.EnvVar {
+	return nil
+}
+
+func (alembicFramework) IngressRules(service string, port int32) []networkingv1.HTTPIngressPath {
+	return prefixPaths(service, port, "/")
+}
+
+func (alembicFramework) IngressAnnotations() map[string]string {
+	return nil
+}
+
+// Probes checks the health check path for readiness as APIs often do not serve /
+func (alembicFramework) Probes(checkPath string, port int32) (*corev1.Probe, *corev1.Probe) {
+	liveness, readiness := httpProbes(checkPath, checkPath, port)
+	readiness.InitialDelaySeconds = 5
+	readiness.PeriodSeconds = 10
+
+	return liveness, readiness
+}
+
+// Resources returns no requests or limits
+func (alembicFramework) Resources() corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{}
+}
+
+func (alembicFramework) Port() int32 {
+	return FrontendPort
+}
+
+// Env sets the SQLAlchemy URL from DATABASE_URL and APP_MODULE from frontend.app_module
+func (a alembicFramework) Env() []corev1.EnvVar {
+	env := []corev1.EnvVar{
+		{
+			Name:  "SQLALCHEMY_DATABASE_URI",
+			Value: "$(DATABASE_URL)",
+		},
+		{
+			Name:  "PORT",
+			Value: strconv.Itoa(int(frontendPort(a))),
+		},
+		secretKeyEnv(),
+	}
+	if module := viper.GetString("frontend.app_module"); module != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "APP_MODULE",
+			Value: module,
+		})
+	}
+
+	return append(env, webConcurrencyEnv()...)
+}
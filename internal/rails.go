@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+
+	"github.com/harvey-earth/pocdeploy/internal/models"
+)
+
+// railsFramework deploys Ruby on Rails apps
+type railsFramework struct{}
+
+func init() {
+	registerFramework(railsFramework{})
+}
+
+func (railsFramework) Name() string {
+	return "ror"
+}
+
+// Dockerfile returns an empty string as the Dockerfile is set with frontend.dockerfile
+func (railsFramework) Dockerfile() string {
+	return ""
+}
+
+// PrepareBuildContext does nothing as Rails apps carry their own Gemfile
+func (railsFramework) PrepareBuildContext(path string) error {
+	return nil
+}
+
+func (railsFramework) MigrateCommand() []string {
+	return []string{
+		"bundle",
+		"exec",
+		"rails",
+		"db:prepare",
+	}
+}
+
+// SeedCommand runs backend.seed.task, such as db:seed
+func (railsFramework) SeedCommand(seed models.BackendSeed) []string {
+	if seed.Task == "" {
+		return nil
+	}
+
+	return []string{
+		"bundle",
+		"exec",
+		"rails",
+		seed.Task,
+	}
+}
+
+// railsAdminScript creates the admin record from the ADMIN_* variables unless one with the same email exists.
+// ADMIN_MODEL selects the model.
+const railsAdminScript = `
+model = ENV.fetch("ADMIN_MODEL").constantize
+email = ENV.fetch("ADMIN_EMAIL")
+if model.exists?(email: email)
+  puts "Admin #{email} already exists, skipping..."
+else
+  user = model.new(email: email, password: ENV.fetch("ADMIN_PASSWORD"))
+  user.password_confirmation = ENV.fetch("ADMIN_PASSWORD") if user.respond_to?(:password_confirmation=)
+  user.username = ENV.fetch("ADMIN_USERNAME") if user.respond_to?(:username=)
+  user.admin = true if user.respond_to?(:admin=)
+  user.save!
+  puts "Admin #{email} created"
+end
+`
+
+// AdminCommand runs the rake task in frontend.admin.task if set, or creates a frontend.admin.model record with rails
+// runner. Rails apps have no common admin model, so nothing runs unless one of them is set.
+func (railsFramework) AdminCommand() []string {
+	if task := viper.GetString("frontend.admin.task"); task != "" {
+		return []string{
+			"bundle",
+			"exec",
+			"rails",
+			task,
+		}
+	}
+	if viper.GetString("frontend.admin.model") == "" {
+		return nil
+	}
+
+	return []string{
+		"bundle",
+		"exec",
+		"rails",
+		"runner",
+		railsAdminScript,
+	}
+}
+
+func (railsFramework) AdminEnv() []corev1.EnvVar {
+	env := adminEnv("ADMIN_USERNAME", "ADMIN_EMAIL", "ADMIN_PASSWORD")
+	if model := viper.GetString("frontend.admin.model"); model != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "ADMIN_MODEL",
+			Value: model,
+		})
+	}
+
+	return env
+}
+
+func (railsFramework) IngressRules(service string, port int32) []networkingv1.HTTPIngressPath {
+	return prefixPaths(service, port, "/")
+}
+
+func (railsFramework) IngressAnnotations() map[string]string {
+	return nil
+}
+
+func (railsFramework) Probes(checkPath string, port int32) (*corev1.Probe, *corev1.Probe) {
+	return httpProbes(checkPath, "/", port)
+}
+
+// Resources returns no requests or limits
+func (railsFramework) Resources() corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{}
+}
+
+func (railsFramework) Port() int32 {
+	return FrontendPort
+}
+
+func (railsFramework) Env() []corev1.EnvVar {
+	return []corev1.EnvVar{
+		secretKeyEnv(),
+	}
+}
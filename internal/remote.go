@@ -0,0 +1,162 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"golang.org/x/term"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// streams holds the standard streams attached to a command run in a pod
+type streams struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	// tty allocates a terminal in the container, stdin must be a terminal
+	tty bool
+}
+
+// terminalStreams returns the process streams, with a tty if stdin is a terminal and tty is requested
+func terminalStreams(tty bool) streams {
+	return streams{
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+		tty:    tty && term.IsTerminal(int(os.Stdin.Fd())),
+	}
+}
+
+// execInPod runs command in a container of pod through the exec API, attaching s
+func execInPod(clientset *kubernetes.Clientset, pod, container string, command []string, s streams) error {
+	config, err := kubernetesRestConfig()
+	if err != nil {
+		return err
+	}
+
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(AppNamespace).
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdin:     s.stdin != nil,
+			Stdout:    s.stdout != nil,
+			Stderr:    s.stderr != nil && !s.tty,
+			TTY:       s.tty,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(config, http.MethodPost, req.URL())
+	if err != nil {
+		err = fmt.Errorf("error creating executor: %w", err)
+		return err
+	}
+
+	options := remotecommand.StreamOptions{
+		Stdin:  s.stdin,
+		Stdout: s.stdout,
+		Tty:    s.tty,
+	}
+	if !s.tty {
+		options.Stderr = s.stderr
+	}
+
+	if s.tty {
+		// Put the local terminal in raw mode so keys are passed through, and follow its size
+		fd := int(os.Stdin.Fd())
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			err = fmt.Errorf("error setting terminal to raw mode: %w", err)
+			return err
+		}
+		defer term.Restore(fd, state)
+
+		sizes := make(terminalSizeQueue, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go sizes.watch(ctx, fd)
+		options.TerminalSizeQueue = sizes
+	}
+
+	return executor.StreamWithContext(context.Background(), options)
+}
+
+// terminalSizeQueue passes local terminal size changes to the exec API
+type terminalSizeQueue chan remotecommand.TerminalSize
+
+// Next returns the next terminal size, or nil once the queue is closed
+func (q terminalSizeQueue) Next() *remotecommand.TerminalSize {
+	size, ok := <-q
+	if !ok {
+		return nil
+	}
+
+	return &size
+}
+
+// watch polls the terminal size as resize signals are not portable, and sends it when it changes
+func (q terminalSizeQueue) watch(ctx context.Context, fd int) {
+	defer close(q)
+	var last remotecommand.TerminalSize
+
+	for {
+		width, height, err := term.GetSize(fd)
+		if err == nil {
+			size := remotecommand.TerminalSize{Width: uint16(width), Height: uint16(height)}
+			if size != last {
+				last = size
+				select {
+				case q <- size:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+// portForward forwards localPort to remotePort of pod until stop is closed. ready is closed once it is listening.
+func portForward(clientset *kubernetes.Clientset, pod string, localPort, remotePort int, stop, ready chan struct{}) error {
+	config, err := kubernetesRestConfig()
+	if err != nil {
+		return err
+	}
+
+	transport, upgrader, err := spdy.RoundTripperFor(config)
+	if err != nil {
+		err = fmt.Errorf("error creating port-forward transport: %w", err)
+		return err
+	}
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(AppNamespace).
+		Name(pod).
+		SubResource("portforward")
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, req.URL())
+
+	ports := []string{fmt.Sprintf("%d:%d", localPort, remotePort)}
+	forwarder, err := portforward.NewOnAddresses(dialer, []string{"localhost"}, ports, stop, ready, io.Discard, os.Stderr)
+	if err != nil {
+		err = fmt.Errorf("error creating port-forward: %w", err)
+		return err
+	}
+
+	return forwarder.ForwardPorts()
+}
@@ -0,0 +1,246 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/harvey-earth/pocdeploy/internal/models"
+)
+
+// ClusterHealthyPhase is the CloudNative PG Cluster phase once every instance is ready
+const ClusterHealthyPhase = "Cluster in healthy state"
+
+// RestoreBackend creates a new cluster recovered from the backup object store and switches the frontend to it.
+// from is the name of a Backup, an RFC 3339 timestamp to recover to, or "latest" to replay all archived WAL.
+// If deleteOld is set the replaced cluster is deleted once the frontend has switched.
+func RestoreBackend(from string, deleteOld bool) error {
+	if err := requireCNPG("restore"); err != nil {
+		return err
+	}
+	Info("Restoring CloudNative PG Cluster")
+
+	cfg, err := loadBackendConfig()
+	if err != nil {
+		return err
+	}
+	if !backupEnabled(cfg) {
+		return fmt.Errorf("backups are not configured, set backend.backup.destination or backend.backup.minio")
+	}
+
+	oldName := activeClusterName()
+	newName := fmt.Sprintf("%s-%s", ClusterName, time.Now().UTC().Format("20060102150405"))
+
+	recovery, externalClusters, err := recoverySpec(cfg, oldName, from)
+	if err != nil {
+		return err
+	}
+	spec := clusterSpec(cfg)
+	spec["bootstrap"] = map[string]any{
+		"recovery": recovery,
+	}
+	if externalClusters != nil {
+		spec["externalClusters"] = externalClusters
+	}
+
+	clientdyn, err := kubernetesDynamicClient()
+	if err != nil {
+		return err
+	}
+	clusters := clientdyn.Resource(postgresGVR).Namespace(AppNamespace)
+
+	postgresCluster := &unstructured.Unstructured{
+		Object: map[string]any{
+			"apiVersion": "postgresql.cnpg.io/v1",
+			"kind":       "Cluster",
+			"metadata": map[string]any{
+				"name":      newName,
+				"namespace": AppNamespace,
+				"labels": map[string]any{
+					"app.kubernetes.io/component": "cluster",
+					"app.kubernetes.io/name":      "backend",
+				},
+			},
+			"spec": spec,
+		},
+	}
+	if _, err = clusters.Create(context.Background(), postgresCluster, metav1.CreateOptions{}); err != nil {
+		err = fmt.Errorf("error creating %s: %w", newName, err)
+		return err
+	}
+	msg := fmt.Sprintf("Cluster %s created from %s, waiting for recovery", newName, from)
+	Info(msg)
+
+	// Recovery replays the base backup and WAL, so allow longer than other waits
+	for i := 1; ; i++ {
+		cluster, err := clusters.Get(context.Background(), newName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		phase, _, _ := unstructured.NestedString(cluster.Object, "status", "phase")
+		if phase == ClusterHealthyPhase {
+			break
+		}
+
+		msg := fmt.Sprintf("Waiting for %s in phase %q %d of %d", newName, phase, i, MaxRetries)
+		Debug(msg)
+		if i >= MaxRetries {
+			return fmt.Errorf("end of retries waiting for %s to recover, %s is still active", newName, oldName)
+		}
+		time.Sleep(time.Duration(i*5) * time.Second)
+	}
+
+	// Poolers are created for the new cluster before the deployments switch to them
+	if err = configurePoolers(cfg, newName); err != nil {
+		err = fmt.Errorf("error configuring poolers for %s: %w", newName, err)
+		return err
+	}
+
+	clientset, err := kubernetesDefaultClient()
+	if err != nil {
+		return err
+	}
+	if err = repointWorkloads(clientset, oldName, newName); err != nil {
+		err = fmt.Errorf("error switching workloads to %s: %w", newName, err)
+		return err
+	}
+	if err = setActiveCluster(clientset, newName); err != nil {
+		err = fmt.Errorf("error recording %s as active: %w", newName, err)
+		return err
+	}
+	if err = configureScheduledBackup(cfg); err != nil {
+		err = fmt.Errorf("error configuring scheduled backup: %w", err)
+		return err
+	}
+
+	if deleteOld {
+		if err = deletePoolers(oldName); err != nil {
+			err = fmt.Errorf("error deleting poolers of %s: %w", oldName, err)
+			return err
+		}
+		if err = clusters.Delete(context.Background(), oldName, metav1.DeleteOptions{}); err != nil {
+			err = fmt.Errorf("error deleting %s: %w", oldName, err)
+			return err
+		}
+		msg := fmt.Sprintf("Deleted %s", oldName)
+		Info(msg)
+	}
+
+	msg = fmt.Sprintf("CloudNative PG Cluster restored to %s", newName)
+	Info(msg)
+	return nil
+}
+
+// recoverySpec renders the recovery bootstrap for from, along with the external cluster pointing at the object store
+// of oldName when recovering from WAL
+func recoverySpec(cfg models.Backend, oldName string, from string) (map[string]any, []any, error) {
+	recovery := map[string]any{
+		"database": cfg.Database,
+		"owner":    cfg.Owner,
+	}
+
+	// A named backup carries its own object store location
+	if from != "latest" {
+		if _, err := time.Parse(time.RFC3339, from); err != nil {
+			if len(validation.IsDNS1123Subdomain(from)) > 0 {
+				return nil, nil, fmt.Errorf("--from %q is not a backup name, RFC 3339 timestamp or latest", from)
+			}
+			recovery["backup"] = map[string]any{
+				"name": from,
+			}
+			return recovery, nil, nil
+		}
+		recovery["recoveryTarget"] = map[string]any{
+			"targetTime": from,
+		}
+	}
+
+	// Clusters archive under their own name, so recover from the active cluster's archive
+	store := backupSpec(cfg.Backup)["barmanObjectStore"].(map[string]any)
+	store["serverName"] = oldName
+	recovery["source"] = "origin"
+	externalClusters := []any{
+		map[string]any{
+			"name":              "origin",
+			"barmanObjectStore": store,
+		},
+	}
+
+	return recovery, externalClusters, nil
+}
+
+// repointWorkloads switches every deployment and CronJob in the app namespace from the secret and read only service of
+// oldName to those of newName
+func repointWorkloads(clientset *kubernetes.Clientset, oldName, newName string) error {
+	deployments := clientset.AppsV1().Deployments(AppNamespace)
+
+	list, err := deployments.List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	for _, deployment := range list.Items {
+		if !repointPodSpec(&deployment.Spec.Template.Spec, oldName, newName) {
+			continue
+		}
+		msg := fmt.Sprintf("Switching deployment %s to %s", deployment.Name, newName)
+		Debug(msg)
+		if _, err = deployments.Update(context.Background(), &deployment, metav1.UpdateOptions{}); err != nil {
+			err = fmt.Errorf("error updating deployment %s: %w", deployment.Name, err)
+			return err
+		}
+	}
+
+	// Jobs already started by a CronJob keep the old cluster, the next ones use newName
+	cronjobs := clientset.BatchV1().CronJobs(AppNamespace)
+	cronList, err := cronjobs.List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	for _, cronjob := range cronList.Items {
+		if !repointPodSpec(&cronjob.Spec.JobTemplate.Spec.Template.Spec, oldName, newName) {
+			continue
+		}
+		msg := fmt.Sprintf("Switching cron %s to %s", cronjob.Name, newName)
+		Debug(msg)
+		if _, err = cronjobs.Update(context.Background(), &cronjob, metav1.UpdateOptions{}); err != nil {
+			err = fmt.Errorf("error updating cron %s: %w", cronjob.Name, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// repointPodSpec replaces references to the cluster oldName in the container env, and returns true if any changed
+func repointPodSpec(spec *corev1.PodSpec, oldName, newName string) bool {
+	changed := false
+	repoint := func(containers []corev1.Container) {
+		for c := range containers {
+			for e := range containers[c].Env {
+				env := &containers[c].Env[e]
+				if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil && env.ValueFrom.SecretKeyRef.Name == oldName+"-app" {
+					env.ValueFrom.SecretKeyRef.Name = newName + "-app"
+					changed = true
+				}
+				for _, service := range []string{"-ro", "-pooler-rw", "-pooler-ro"} {
+					if env.Value == oldName+service {
+						env.Value = newName + service
+						changed = true
+					}
+				}
+			}
+		}
+	}
+	repoint(spec.InitContainers)
+	repoint(spec.Containers)
+
+	return changed
+}
@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/viper"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// RunTTL is how long finished run jobs are kept, in seconds, so their logs can still be read
+const RunTTL = 600
+
+// ExecFrontend runs command in the frontend container of a ready frontend pod, with a terminal if tty is set and
+// stdin is one. The exit code of the command is returned.
+func ExecFrontend(command []string, tty bool) (int, error) {
+	clientset, err := kubernetesDefaultClient()
+	if err != nil {
+		return 1, err
+	}
+	pod, err := frontendPod(clientset)
+	if err != nil {
+		return 1, err
+	}
+
+	msg := fmt.Sprintf("Running %v in %s", command, pod)
+	Debug(msg)
+	return exitCode(execInPod(clientset, pod, "frontend", command, terminalStreams(tty)))
+}
+
+// frontendPod returns the name of a running frontend pod whose frontend container is ready
+func frontendPod(clientset *kubernetes.Clientset) (string, error) {
+	pods, err := clientset.CoreV1().Pods(AppNamespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: "app.kubernetes.io/name=" + viper.GetString("frontend.image"),
+	})
+	if err != nil {
+		err = fmt.Errorf("error listing frontend pods: %w", err)
+		return "", err
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+			continue
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name == "frontend" && status.Ready {
+				return pod.Name, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no ready frontend pod found")
+}
+
+// exitCode returns the exit code of a command run with execInPod, only returning an error if it couldn't be run
+func exitCode(err error) (int, error) {
+	if err == nil {
+		return 0, nil
+	}
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus(), nil
+	}
+
+	return 1, err
+}
+
+// RunCommand runs command in a throwaway job from the frontend image with the same variables and config files as
+// the frontend, streams its output to w, and returns its exit code. The job runs once without retries and is
+// removed RunTTL seconds after it finishes.
+func RunCommand(fw Framework, command []string, w io.Writer) (int, error) {
+	clientset, err := kubernetesDefaultClient()
+	if err != nil {
+		return 1, err
+	}
+	cfg, err := loadAppConfig()
+	if err != nil {
+		err = fmt.Errorf("error with frontend config: %w", err)
+		return 1, err
+	}
+
+	template := podTemplate(fw, cfg, workload{
+		name:    "run",
+		command: command,
+	})
+	template.Labels["app.kubernetes.io/component"] = "run"
+	template.Spec.RestartPolicy = corev1.RestartPolicyNever
+
+	var backoffLimit int32
+	var ttl int32 = RunTTL
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			// The API server adds a random suffix, so concurrent runs can't collide
+			GenerateName: "run-",
+			Namespace:    AppNamespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/component": "run",
+				"app.kubernetes.io/name":      "run",
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			TTLSecondsAfterFinished: &ttl,
+			Template:                template,
+		},
+	}
+	created, err := clientset.BatchV1().Jobs(AppNamespace).Create(context.Background(), job, metav1.CreateOptions{})
+	if err != nil {
+		err = fmt.Errorf("error creating run job: %w", err)
+		return 1, err
+	}
+	msg := fmt.Sprintf("Started job %s", created.Name)
+	Debug(msg)
+
+	pod, err := streamJobLogs(clientset, created.Name, w)
+	if err != nil {
+		return 1, err
+	}
+
+	return containerExitCode(clientset, pod, "run")
+}
+
+// containerExitCode waits for container of pod to terminate and returns its exit code
+func containerExitCode(clientset *kubernetes.Clientset, pod, container string) (int, error) {
+	for i := 1; ; i++ {
+		p, err := clientset.CoreV1().Pods(AppNamespace).Get(context.Background(), pod, metav1.GetOptions{})
+		if err != nil {
+			err = fmt.Errorf("error getting pod %s: %w", pod, err)
+			return 1, err
+		}
+		for _, status := range p.Status.ContainerStatuses {
+			if status.Name == container && status.State.Terminated != nil {
+				return int(status.State.Terminated.ExitCode), nil
+			}
+		}
+
+		msg := fmt.Sprintf("Waiting for %s to exit %d of %d", pod, i, MaxRetries)
+		Debug(msg)
+		if i >= MaxRetries {
+			return 1, fmt.Errorf("end of retries waiting for %s to exit", pod)
+		}
+		time.Sleep(time.Duration(i*2) * time.Second)
+	}
+}
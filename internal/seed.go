@@ -0,0 +1,234 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/harvey-earth/pocdeploy/internal/models"
+)
+
+// SeedMountPath is where the seed file is mounted in the backend-seed job
+const SeedMountPath = "/seed"
+
+// SeedBackend loads backend.seed once the migration job has completed. Seed files that fit in a configmap are loaded
+// by a job, larger ones are streamed to the primary. Fixtures and tasks run in a job from the frontend image.
+func SeedBackend(fw Framework) error {
+	cfg, err := loadBackendConfig()
+	if err != nil {
+		return err
+	}
+	seed := cfg.Seed
+	if seed.File == "" && seed.Fixture == "" && seed.Task == "" {
+		return nil
+	}
+	Info("Seeding backend")
+
+	clientset, err := kubernetesDefaultClient()
+	if err != nil {
+		return err
+	}
+
+	if err = waitForJob(clientset, "backend-init"); err != nil {
+		err = fmt.Errorf("error waiting for migrations: %w", err)
+		return err
+	}
+
+	if seed.File != "" {
+		content, err := os.ReadFile(seed.File)
+		if err != nil {
+			err = fmt.Errorf("error reading seed file: %w", err)
+			return err
+		}
+		// Configmaps are limited to 1MiB
+		if len(content) > 1024*1024 {
+			err = seedPrimary(clientset, cfg, content)
+		} else {
+			err = seedJob(clientset, cfg, content)
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		command := seedCommand(fw, seed)
+		if command == nil {
+			field := "fixture"
+			if seed.Task != "" {
+				field = "task"
+			}
+			return fmt.Errorf("backend.seed.%s is not supported for %s", field, fw.Name())
+		}
+
+		appCfg, err := loadAppConfig()
+		if err != nil {
+			err = fmt.Errorf("error with frontend config: %w", err)
+			return err
+		}
+		template := podTemplate(fw, appCfg, workload{
+			name:    "backend-seed",
+			command: command,
+		})
+		if err = runSeedJob(clientset, template); err != nil {
+			return err
+		}
+	}
+
+	Info("Backend seeded")
+	return nil
+}
+
+// seedCommand returns the framework command for a fixture or task, or nil if it isn't supported
+func seedCommand(fw Framework, seed models.BackendSeed) []string {
+	if s, ok := fw.(seeder); ok {
+		return s.SeedCommand(seed)
+	}
+
+	return nil
+}
+
+// seedJob ships the seed file in the backend-seed configmap and loads it with psql or pg_restore from the Postgres
+// image, connecting as the application user
+func seedJob(clientset *kubernetes.Clientset, cfg models.Backend, content []byte) error {
+	name := invalidConfigMapKey.ReplaceAllString(filepath.Base(cfg.Seed.File), "_")
+	Debug("Creating backend seed configmap")
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backend-seed",
+			Namespace: AppNamespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/component": "configmap",
+				"app.kubernetes.io/name":      "backend-seed",
+			},
+		},
+		BinaryData: map[string][]byte{
+			name: content,
+		},
+	}
+	configMaps := clientset.CoreV1().ConfigMaps(AppNamespace)
+	if err := configMaps.Delete(context.Background(), configMap.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if _, err := configMaps.Create(context.Background(), configMap, metav1.CreateOptions{}); err != nil {
+		err = fmt.Errorf("error creating backend-seed configmap: %w", err)
+		return err
+	}
+
+	path := SeedMountPath + "/" + name
+	command := []string{"psql", "$(DATABASE_URL)", "-v", "ON_ERROR_STOP=1", "-f", path}
+	if filepath.Ext(name) == ".dump" {
+		command = []string{"pg_restore", "--no-owner", "--no-privileges", "-d", "$(DATABASE_URL)", path}
+	}
+
+	image := postgresImage(cfg)
+	if image == "" {
+		// Newer clients can load dumps from older servers
+		image = PostgresImage + ":17"
+	}
+
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{
+				"app.kubernetes.io/name": "backend-seed",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:    "backend-seed",
+					Image:   image,
+					Command: command,
+					Env:     databaseEnv(false, false),
+					VolumeMounts: []corev1.VolumeMount{
+						{
+							Name:      "seed",
+							MountPath: SeedMountPath,
+							ReadOnly:  true,
+						},
+					},
+				},
+			},
+			Volumes: []corev1.Volume{
+				{
+					Name: "seed",
+					VolumeSource: corev1.VolumeSource{
+						ConfigMap: &corev1.ConfigMapVolumeSource{
+							LocalObjectReference: corev1.LocalObjectReference{
+								Name: "backend-seed",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	return runSeedJob(clientset, template)
+}
+
+// runSeedJob runs the backend-seed job once, as seed data is usually not safe to load twice, and waits for it
+func runSeedJob(clientset *kubernetes.Clientset, template corev1.PodTemplateSpec) error {
+	var backoffLimit int32 = 0
+	template.Spec.RestartPolicy = corev1.RestartPolicyNever
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backend-seed",
+			Namespace: AppNamespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/component": "job",
+				"app.kubernetes.io/name":      "backend-seed",
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template:     template,
+		},
+	}
+	if err := recreateJob(clientset, job); err != nil {
+		err = fmt.Errorf("error creating backend-seed job: %w", err)
+		return err
+	}
+
+	return waitForJob(clientset, "backend-seed")
+}
+
+// seedPrimary streams a seed file too large for a configmap to psql or pg_restore on the primary with kubectl exec,
+// setting the role to the database owner so the app can use the loaded tables
+func seedPrimary(clientset *kubernetes.Clientset, cfg models.Backend, content []byte) error {
+	msg := fmt.Sprintf("Streaming %s to the primary", cfg.Seed.File)
+	Debug(msg)
+
+	primary, err := primaryPod(clientset)
+	if err != nil {
+		return err
+	}
+
+	command := []string{"psql", "-v", "ON_ERROR_STOP=1", "-d", cfg.Database, "-c", "SET ROLE " + cfg.Owner, "-f", "-"}
+	if filepath.Ext(cfg.Seed.File) == ".dump" {
+		command = []string{"pg_restore", "--no-owner", "--no-privileges", "--role=" + cfg.Owner, "-d", cfg.Database}
+	}
+
+	var stderr bytes.Buffer
+	err = execInPod(clientset, primary, "postgres", command, streams{
+		stdin:  bytes.NewReader(content),
+		stdout: io.Discard,
+		stderr: &stderr,
+	})
+	if err != nil {
+		err = fmt.Errorf("error loading %s in %s: %w: %s", cfg.Seed.File, primary, err, strings.TrimSpace(stderr.String()))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,459 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/spf13/viper"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/harvey-earth/pocdeploy/internal/models"
+)
+
+// RedisName is the name of the Redis deployment and service
+const RedisName = "redis"
+
+// RedisImage is the default Redis image
+const RedisImage = "redis:7.4-alpine"
+
+// RedisExporterImage is the Prometheus exporter run next to Redis
+const RedisExporterImage = "oliver006/redis_exporter:v1.66.0"
+
+// RabbitMQName is the name of the RabbitMQ deployment and service
+const RabbitMQName = "rabbitmq"
+
+// RabbitMQImage is the default RabbitMQ image, which has the Prometheus plugin enabled
+const RabbitMQImage = "rabbitmq:3.13-management-alpine"
+
+// RabbitMQSecretName is the secret holding the generated RabbitMQ credentials
+const RabbitMQSecretName = "rabbitmq-credentials"
+
+// redisMemory matches the memory units accepted by the Redis maxmemory directive
+var redisMemory = regexp.MustCompile(`^[0-9]+([kKmMgG][bB]?)?$`)
+
+// loadServicesConfig reads the services config section, setting defaults and validating it
+func loadServicesConfig() (models.Services, error) {
+	var cfg models.Services
+	if err := viper.UnmarshalKey("services", &cfg); err != nil {
+		err = fmt.Errorf("error reading services config: %w", err)
+		return cfg, err
+	}
+
+	if cfg.Redis.Image == "" {
+		cfg.Redis.Image = RedisImage
+	}
+	if cfg.RabbitMQ.Image == "" {
+		cfg.RabbitMQ.Image = RabbitMQImage
+	}
+
+	storage := map[string]string{
+		"services.redis.storage":    cfg.Redis.Storage,
+		"services.rabbitmq.storage": cfg.RabbitMQ.Storage,
+	}
+	for key, value := range storage {
+		if value == "" {
+			continue
+		}
+		if _, err := resource.ParseQuantity(value); err != nil {
+			err = fmt.Errorf("invalid %s %q: %w", key, value, err)
+			return cfg, err
+		}
+	}
+	if cfg.Redis.MaxMemory != "" && !redisMemory.MatchString(cfg.Redis.MaxMemory) {
+		err := fmt.Errorf("invalid services.redis.max_memory %q, expected a size such as 256mb", cfg.Redis.MaxMemory)
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+// redisEnabled returns true if services.redis.enabled is set
+func redisEnabled() bool {
+	return viper.GetBool("services.redis.enabled")
+}
+
+// rabbitMQEnabled returns true if services.rabbitmq.enabled is set
+func rabbitMQEnabled() bool {
+	return viper.GetBool("services.rabbitmq.enabled")
+}
+
+// servicesEnv returns REDIS_URL and the RABBITMQ_* variables for the enabled services
+func servicesEnv() []corev1.EnvVar {
+	env := []corev1.EnvVar{}
+	if redisEnabled() {
+		env = append(env, corev1.EnvVar{
+			Name:  "REDIS_URL",
+			Value: fmt.Sprintf("redis://%s:6379/0", RedisName),
+		})
+	}
+	if rabbitMQEnabled() {
+		env = append(env,
+			secretEnvVar("RABBITMQ_USER", RabbitMQSecretName, "username"),
+			secretEnvVar("RABBITMQ_PASSWORD", RabbitMQSecretName, "password"),
+			corev1.EnvVar{
+				Name:  "RABBITMQ_URL",
+				Value: fmt.Sprintf("amqp://$(RABBITMQ_USER):$(RABBITMQ_PASSWORD)@%s:5672/", RabbitMQName),
+			},
+		)
+	}
+
+	return env
+}
+
+// secretEnvVar returns a variable read from key of secret
+func secretEnvVar(name, secret, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: secret,
+				},
+				Key: key,
+			},
+		},
+	}
+}
+
+// CheckServicesConfig validates the services config section so mistakes are caught before anything is created
+func CheckServicesConfig() error {
+	_, err := loadServicesConfig()
+	return err
+}
+
+// ConfigureServices creates or updates the enabled services, and removes the deployments of disabled ones. Volume
+// claims are kept so data survives disabling a service.
+func ConfigureServices() error {
+	cfg, err := loadServicesConfig()
+	if err != nil {
+		return err
+	}
+	Info("Configuring services")
+
+	clientset, err := kubernetesDefaultClient()
+	if err != nil {
+		return err
+	}
+
+	if cfg.Redis.Enabled {
+		err = deployRedis(clientset, cfg.Redis)
+	} else {
+		err = removeService(clientset, RedisName)
+	}
+	if err != nil {
+		err = fmt.Errorf("error configuring Redis: %w", err)
+		return err
+	}
+
+	if cfg.RabbitMQ.Enabled {
+		err = deployRabbitMQ(clientset, cfg.RabbitMQ)
+	} else {
+		err = removeService(clientset, RabbitMQName)
+	}
+	if err != nil {
+		err = fmt.Errorf("error configuring RabbitMQ: %w", err)
+		return err
+	}
+
+	Info("Services configured")
+	return nil
+}
+
+// deployRedis deploys Redis with an exporter sidecar. Without storage Redis runs as a cache and persistence is off.
+func deployRedis(clientset *kubernetes.Clientset, redis models.Redis) error {
+	Debug("Deploying Redis")
+
+	args := []string{"redis-server", "--save", "", "--appendonly", "no"}
+	if redis.Storage != "" {
+		args = []string{"redis-server", "--appendonly", "yes"}
+	}
+	if redis.MaxMemory != "" {
+		args = append(args, "--maxmemory", redis.MaxMemory)
+	}
+
+	containers := []corev1.Container{
+		{
+			Name:  RedisName,
+			Image: redis.Image,
+			Args:  args,
+			Ports: []corev1.ContainerPort{
+				{
+					Name:          "redis",
+					ContainerPort: 6379,
+					Protocol:      corev1.ProtocolTCP,
+				},
+			},
+			ReadinessProbe: &corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{
+					Exec: &corev1.ExecAction{
+						Command: []string{"redis-cli", "ping"},
+					},
+				},
+				PeriodSeconds: 10,
+			},
+			VolumeMounts: []corev1.VolumeMount{
+				{
+					Name:      "data",
+					MountPath: "/data",
+				},
+			},
+		},
+		{
+			Name:  "exporter",
+			Image: RedisExporterImage,
+			Env: []corev1.EnvVar{
+				{
+					Name:  "REDIS_ADDR",
+					Value: "redis://localhost:6379",
+				},
+			},
+			Ports: []corev1.ContainerPort{
+				{
+					Name:          "metrics",
+					ContainerPort: 9121,
+					Protocol:      corev1.ProtocolTCP,
+				},
+			},
+		},
+	}
+	ports := []corev1.ServicePort{
+		{
+			Name:       "redis",
+			Port:       6379,
+			TargetPort: intstr.FromInt32(6379),
+			Protocol:   corev1.ProtocolTCP,
+		},
+	}
+
+	if err := applyService(clientset, RedisName, redis.Storage, containers, ports); err != nil {
+		return err
+	}
+
+	Debug("Redis deployed")
+	return nil
+}
+
+// deployRabbitMQ deploys RabbitMQ with generated credentials, which are reused when it is reconfigured
+func deployRabbitMQ(clientset *kubernetes.Clientset, rabbitmq models.RabbitMQ) error {
+	Debug("Deploying RabbitMQ")
+
+	password, err := storedPassword(clientset, RabbitMQSecretName, "password")
+	if err != nil {
+		return err
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      RabbitMQSecretName,
+			Namespace: AppNamespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/component": "secret",
+				"app.kubernetes.io/name":      RabbitMQName,
+			},
+		},
+		StringData: map[string]string{
+			"username": "pocdeploy",
+			"password": password,
+		},
+		Type: corev1.SecretTypeOpaque,
+	}
+	_, err = clientset.CoreV1().Secrets(AppNamespace).Create(context.Background(), secret, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		_, err = clientset.CoreV1().Secrets(AppNamespace).Update(context.Background(), secret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		err = fmt.Errorf("error creating %s secret: %w", RabbitMQSecretName, err)
+		return err
+	}
+
+	containers := []corev1.Container{
+		{
+			Name:  RabbitMQName,
+			Image: rabbitmq.Image,
+			Env: []corev1.EnvVar{
+				secretEnvVar("RABBITMQ_DEFAULT_USER", RabbitMQSecretName, "username"),
+				secretEnvVar("RABBITMQ_DEFAULT_PASS", RabbitMQSecretName, "password"),
+				// The data directory is named after the node, which would otherwise change with the pod hostname
+				{
+					Name:  "RABBITMQ_NODENAME",
+					Value: "rabbit@localhost",
+				},
+			},
+			Ports: []corev1.ContainerPort{
+				{
+					Name:          "amqp",
+					ContainerPort: 5672,
+					Protocol:      corev1.ProtocolTCP,
+				},
+				{
+					Name:          "management",
+					ContainerPort: 15672,
+					Protocol:      corev1.ProtocolTCP,
+				},
+				{
+					Name:          "metrics",
+					ContainerPort: 15692,
+					Protocol:      corev1.ProtocolTCP,
+				},
+			},
+			ReadinessProbe: &corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{
+					Exec: &corev1.ExecAction{
+						Command: []string{"rabbitmq-diagnostics", "-q", "ping"},
+					},
+				},
+				PeriodSeconds:  10,
+				TimeoutSeconds: 10,
+			},
+			VolumeMounts: []corev1.VolumeMount{
+				{
+					Name:      "data",
+					MountPath: "/var/lib/rabbitmq",
+				},
+			},
+		},
+	}
+	ports := []corev1.ServicePort{
+		{
+			Name:       "amqp",
+			Port:       5672,
+			TargetPort: intstr.FromInt32(5672),
+			Protocol:   corev1.ProtocolTCP,
+		},
+		{
+			Name:       "management",
+			Port:       15672,
+			TargetPort: intstr.FromInt32(15672),
+			Protocol:   corev1.ProtocolTCP,
+		},
+	}
+
+	if err = applyService(clientset, RabbitMQName, rabbitmq.Storage, containers, ports); err != nil {
+		return err
+	}
+
+	Debug("RabbitMQ deployed")
+	return nil
+}
+
+// applyService creates or updates a single replica deployment of containers and its service. The data volume is a
+// volume claim of storage size, or an emptyDir if storage is empty.
+func applyService(clientset *kubernetes.Clientset, name, storage string, containers []corev1.Container, ports []corev1.ServicePort) error {
+	labels := map[string]string{
+		"app.kubernetes.io/component": "service",
+		"app.kubernetes.io/name":      name,
+	}
+	selector := map[string]string{
+		"app.kubernetes.io/name": name,
+	}
+
+	volume := corev1.Volume{
+		Name: "data",
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		},
+	}
+	if storage != "" {
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name + "-data",
+				Namespace: AppNamespace,
+				Labels:    labels,
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceStorage: resource.MustParse(storage),
+					},
+				},
+			},
+		}
+		if _, err := clientset.CoreV1().PersistentVolumeClaims(AppNamespace).Create(context.Background(), pvc, metav1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+			err = fmt.Errorf("error creating %s volume claim: %w", name, err)
+			return err
+		}
+		volume.VolumeSource = corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: pvc.Name,
+			},
+		}
+	}
+
+	var reps int32 = 1
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: AppNamespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &reps,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: selector,
+			},
+			// The volume can only be mounted by one pod
+			Strategy: appsv1.DeploymentStrategy{
+				Type: appsv1.RecreateDeploymentStrategyType,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					Containers: containers,
+					Volumes:    []corev1.Volume{volume},
+				},
+			},
+		},
+	}
+	deployments := clientset.AppsV1().Deployments(AppNamespace)
+	_, err := deployments.Create(context.Background(), deployment, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		_, err = deployments.Update(context.Background(), deployment, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		err = fmt.Errorf("error creating %s deployment: %w", name, err)
+		return err
+	}
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: AppNamespace,
+			Labels:    labels,
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: selector,
+			Ports:    ports,
+		},
+	}
+	if _, err := clientset.CoreV1().Services(AppNamespace).Create(context.Background(), service, metav1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+		err = fmt.Errorf("error creating %s service: %w", name, err)
+		return err
+	}
+
+	return nil
+}
+
+// removeService deletes the deployment and service of a disabled service
+func removeService(clientset *kubernetes.Clientset, name string) error {
+	err := clientset.AppsV1().Deployments(AppNamespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		err = fmt.Errorf("error deleting %s deployment: %w", name, err)
+		return err
+	}
+	err = clientset.CoreV1().Services(AppNamespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		err = fmt.Errorf("error deleting %s service: %w", name, err)
+		return err
+	}
+
+	return nil
+}
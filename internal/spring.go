@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// springFramework deploys Spring Boot apps that migrate with Flyway
+type springFramework struct{}
+
+func init() {
+	registerFramework(springFramework{})
+}
+
+func (springFramework) Name() string {
+	return "spring"
+}
+
+func (springFramework) Dockerfile() string {
+	return "frontend/spring/Dockerfile"
+}
+
+// PrepareBuildContext does nothing as Spring apps carry their own Maven or Gradle wrapper
+func (springFramework) PrepareBuildContext(path string) error {
+	return nil
+}
+
+// MigrateCommand runs Flyway against the mounted migrations
+func (springFramework) MigrateCommand() []string {
+	return []string{
+		"flyway",
+		"-url=" + jdbcURL(),
+		"-user=$(DATABASE_USER)",
+		"-password=$(DATABASE_PASSWORD)",
+		"-locations=filesystem:" + MigrationsMountPath,
+		"migrate",
+	}
+}
+
+func (springFramework) MigrateImage() string {
+	return "flyway/flyway:10"
+}
+
+func (springFramework) MigrationsDir() string {
+	return "src/main/resources/db/migration"
+}
+
+// AdminCommand returns nil as Spring apps have no common admin user
+func (springFramework) AdminCommand() []string {
+	return nil
+}
+
+func (springFramework) AdminEnv() []corev1.EnvVar {
+	return nil
+}
+
+func (springFramework) IngressRules(service string, port int32) []networkingv1.HTTPIngressPath {
+	return prefixPaths(service, port, "/")
+}
+
+func (springFramework) IngressAnnotations() map[string]string {
+	return nil
+}
+
+// Probes gives the JVM time to start before checking the health check path, such as /actuator/health
+func (springFramework) Probes(checkPath string, port int32) (*corev1.Probe, *corev1.Probe) {
+	liveness, readiness := httpProbes(checkPath, checkPath, port)
+	liveness.InitialDelaySeconds = 90
+	liveness.PeriodSeconds = 20
+	liveness.TimeoutSeconds = 5
+	readiness.InitialDelaySeconds = 30
+	readiness.PeriodSeconds = 10
+	readiness.TimeoutSeconds = 5
+	readiness.FailureThreshold = 6
+
+	return liveness, readiness
+}
+
+// Resources requests enough memory for the JVM heap, which is sized from the limit
+func (springFramework) Resources() corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+			corev1.ResourceMemory: resource.MustParse("768Mi"),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("1Gi"),
+		},
+	}
+}
+
+func (springFramework) Port() int32 {
+	return 8080
+}
+
+// Env sets the Spring datasource from the database variables and disables Flyway on startup as the job migrates
+func (s springFramework) Env() []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{
+			Name:  "SPRING_DATASOURCE_URL",
+			Value: jdbcURL(),
+		},
+		{
+			Name:  "SPRING_DATASOURCE_USERNAME",
+			Value: "$(DATABASE_USER)",
+		},
+		{
+			Name:  "SPRING_DATASOURCE_PASSWORD",
+			Value: "$(DATABASE_PASSWORD)",
+		},
+		{
+			Name:  "SPRING_FLYWAY_ENABLED",
+			Value: "false",
+		},
+		{
+			Name:  "SERVER_PORT",
+			Value: strconv.Itoa(int(frontendPort(s))),
+		},
+		secretKeyEnv(),
+	}
+}
+
+// jdbcURL returns a JDBC URL composed from the database variables
+func jdbcURL() string {
+	url := "jdbc:postgresql://$(DATABASE_HOST):$(DATABASE_PORT)/$(DATABASE_NAME)"
+	if sslmode := databaseSSLMode(); sslmode != "" {
+		url += "?sslmode=" + sslmode
+	}
+
+	return url
+}
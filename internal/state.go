@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// StateConfigMapName is the configmap recording state that changes after create, such as the active cluster
+const StateConfigMapName = "pocdeploy-state"
+
+// activeCluster caches the active CloudNative PG cluster name once it is read
+var activeCluster string
+
+// activeClusterName returns the name of the CloudNative PG cluster the frontend uses. This is ClusterName until a
+// restore replaces it, after which it is read from the state configmap.
+func activeClusterName() string {
+	if activeCluster != "" {
+		return activeCluster
+	}
+	activeCluster = ClusterName
+
+	clientset, err := kubernetesDefaultClient()
+	if err != nil {
+		msg := fmt.Sprintf("Using default cluster name, error creating client: %s", err)
+		Debug(msg)
+		return activeCluster
+	}
+	state, err := clientset.CoreV1().ConfigMaps(AppNamespace).Get(context.Background(), StateConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			msg := fmt.Sprintf("Using default cluster name, error reading %s: %s", StateConfigMapName, err)
+			Debug(msg)
+		}
+		return activeCluster
+	}
+	if name := state.Data["cluster"]; name != "" {
+		activeCluster = name
+	}
+
+	return activeCluster
+}
+
+// databaseSecretName returns the secret CloudNative PG creates with the application database credentials of the
+// active cluster
+func databaseSecretName() string {
+	return activeClusterName() + "-app"
+}
+
+// setActiveCluster records name as the active cluster in the state configmap
+func setActiveCluster(clientset *kubernetes.Clientset, name string) error {
+	configMaps := clientset.CoreV1().ConfigMaps(AppNamespace)
+
+	state, err := configMaps.Get(context.Background(), StateConfigMapName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		state = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      StateConfigMapName,
+				Namespace: AppNamespace,
+				Labels: map[string]string{
+					"app.kubernetes.io/component": "configmap",
+					"app.kubernetes.io/name":      StateConfigMapName,
+				},
+			},
+			Data: map[string]string{
+				"cluster": name,
+			},
+		}
+		_, err = configMaps.Create(context.Background(), state, metav1.CreateOptions{})
+	} else if err == nil {
+		if state.Data == nil {
+			state.Data = map[string]string{}
+		}
+		state.Data["cluster"] = name
+		_, err = configMaps.Update(context.Background(), state, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+
+	activeCluster = name
+	return nil
+}
@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/viper"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/harvey-earth/pocdeploy/internal/models"
+)
+
+// GetStatus reads the state of the frontend, workers, backend and enabled services
+func GetStatus() ([]models.ComponentStatus, error) {
+	clientset, err := kubernetesDefaultClient()
+	if err != nil {
+		return nil, err
+	}
+
+	frontend, err := deploymentStatus(clientset, "frontend", "frontend-deployment")
+	if err != nil {
+		return nil, err
+	}
+	statuses := []models.ComponentStatus{frontend}
+
+	workers, err := loadWorkers()
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range workers {
+		status, err := deploymentStatus(clientset, "worker", WorkerPrefix+w.Name)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+
+	b, err := getBackend(backendType())
+	if err != nil {
+		return nil, err
+	}
+	backend, err := b.Status()
+	if errors.IsNotFound(err) {
+		backend.Status = "NotFound"
+	} else if err != nil {
+		return nil, err
+	}
+	statuses = append(statuses, backend)
+
+	services := []struct {
+		component string
+		name      string
+		enabled   bool
+	}{
+		{"service", RedisName, redisEnabled()},
+		{"service", RabbitMQName, rabbitMQEnabled()},
+		{"backup", MinIOName, viper.GetBool("backend.backup.minio")},
+	}
+	for _, s := range services {
+		if !s.enabled {
+			continue
+		}
+		status, err := deploymentStatus(clientset, s.component, s.name)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// deploymentStatus reads the ready replicas and availability of a deployment in the app namespace
+func deploymentStatus(clientset *kubernetes.Clientset, component, name string) (models.ComponentStatus, error) {
+	status := models.ComponentStatus{
+		Component: component,
+		Name:      name,
+	}
+
+	deployment, err := clientset.AppsV1().Deployments(AppNamespace).Get(context.Background(), name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		status.Status = "NotFound"
+		return status, nil
+	} else if err != nil {
+		err = fmt.Errorf("error getting %s deployment: %w", name, err)
+		return status, err
+	}
+
+	var replicas int32
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status.Ready = fmt.Sprintf("%d/%d", deployment.Status.ReadyReplicas, replicas)
+	status.Status = "Unavailable"
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentAvailable && condition.Status == corev1.ConditionTrue {
+			status.Status = "Available"
+		}
+	}
+
+	return status, nil
+}
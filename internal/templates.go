@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/spf13/viper"
+
+	"github.com/harvey-earth/pocdeploy/internal/models"
+)
+
+// templateValues returns the values from pocdeploy.yaml that patch and overlay templates are rendered against
+func templateValues() models.TemplateValues {
+	host := viper.GetString("frontend.host")
+	if host == "" {
+		host = "localhost"
+	}
+	port := int32(FrontendPort)
+	if fw, err := GetFramework(viper.GetString("frontend.type")); err == nil {
+		port = frontendPort(fw)
+	}
+
+	return models.TemplateValues{
+		Name:      viper.GetString("name"),
+		Namespace: AppNamespace,
+		Host:      host,
+		CheckPath: viper.GetString("frontend.check_path"),
+		Port:      int(port),
+		Image:     viper.GetString("frontend.image"),
+		Version:   viper.GetString("frontend.version"),
+		Secrets: models.TemplateSecrets{
+			Database:  databaseSecretName(),
+			SecretKey: SecretKeySecretName,
+		},
+		Env: models.TemplateEnv{
+			DatabaseName:     "DATABASE_NAME",
+			DatabaseUser:     "DATABASE_USER",
+			DatabasePassword: "DATABASE_PASSWORD",
+			DatabaseHost:     "DATABASE_HOST",
+			DatabasePort:     "DATABASE_PORT",
+			DatabaseURL:      "DATABASE_URL",
+			DatabaseEngine:   "DATABASE_ENGINE",
+			SecretKey:        "SECRET_KEY",
+		},
+	}
+}
+
+// renderTemplate renders content as a Go template against the pocdeploy configuration
+func renderTemplate(name string, content []byte) ([]byte, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		err = fmt.Errorf("error parsing template %s: %w", name, err)
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, templateValues()); err != nil {
+		err = fmt.Errorf("error executing template %s: %w", name, err)
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -7,21 +7,51 @@ import (
 	"github.com/spf13/viper"
 	v1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 )
 
-// CreateDjangoAdminUser creates a job in a Django container to create an admin user using the variables from pocdeploy.yaml
-func CreateDjangoAdminUser() error {
+// CreateAdminUser creates a job in the frontend container to create an admin user using the variables from pocdeploy.yaml
+func CreateAdminUser(fw Framework) error {
+	command := fw.AdminCommand()
+	if command == nil {
+		msg := fmt.Sprintf("Admin user creation is not supported or configured for %s, skipping...", fw.Name())
+		Info(msg)
+		return nil
+	}
+
 	Info("Creating admin user creation job")
 
-	createStr := "from django.contrib.auth import get_user_model;User = get_user_model();User.objects.create_superuser('" + viper.GetString("frontend.admin.username") + "', '" + viper.GetString("frontend.admin.email") + "', '" + viper.GetString("frontend.admin.password") + "');"
 	var backoffLimit int32 = 10
-	imgStr := viper.GetString("frontend.image") + ":" + viper.GetString("frontend.version")
 
 	clientset, err := kubernetesDefaultClient()
 	if err != nil {
 		return err
 	}
+	cfg, err := loadAppConfig()
+	if err != nil {
+		err = fmt.Errorf("error with frontend config: %w", err)
+		return err
+	}
+
+	// Credentials are only passed to the job through the secret
+	generated, err := adminSecret(clientset)
+	if err != nil {
+		err = fmt.Errorf("error creating admin credentials secret: %w", err)
+		return err
+	}
+	if generated != "" {
+		// Printed once, use the credentials command to retrieve it later
+		fmt.Printf("Generated password for admin user %s: %s\n", viper.GetString("frontend.admin.username"), generated)
+	}
+
+	template := podTemplate(fw, cfg, workload{
+		name:    "create-admin",
+		command: command,
+		env:     fw.AdminEnv(),
+	})
+	template.Spec.RestartPolicy = corev1.RestartPolicyOnFailure
 
 	job := &v1.Job{
 		ObjectMeta: metav1.ObjectMeta{
@@ -34,77 +64,11 @@ func CreateDjangoAdminUser() error {
 		},
 		Spec: v1.JobSpec{
 			BackoffLimit: &backoffLimit,
-			Template: corev1.PodTemplateSpec{
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{
-							Name:  "create-admin",
-							Image: imgStr,
-							Command: []string{
-								"/env/bin/python",
-								"manage.py",
-								"shell",
-								"--command",
-								createStr,
-							},
-							Env: []corev1.EnvVar{
-								{
-									Name: "DATABASE_NAME",
-									ValueFrom: &corev1.EnvVarSource{
-										SecretKeyRef: &corev1.SecretKeySelector{
-											LocalObjectReference: corev1.LocalObjectReference{
-												Name: "poc-backend-cluster-app",
-											},
-											Key: "dbname",
-										},
-									},
-								},
-								{
-									Name: "DATABASE_USER",
-									ValueFrom: &corev1.EnvVarSource{
-										SecretKeyRef: &corev1.SecretKeySelector{
-											LocalObjectReference: corev1.LocalObjectReference{
-												Name: "poc-backend-cluster-app",
-											},
-											Key: "username",
-										},
-									},
-								},
-								{
-									Name: "DATABASE_PASSWORD",
-									ValueFrom: &corev1.EnvVarSource{
-										SecretKeyRef: &corev1.SecretKeySelector{
-											LocalObjectReference: corev1.LocalObjectReference{
-												Name: "poc-backend-cluster-app",
-											},
-											Key: "password",
-										},
-									},
-								},
-								{
-									Name: "DATABASE_HOST",
-									ValueFrom: &corev1.EnvVarSource{
-										SecretKeyRef: &corev1.SecretKeySelector{
-											LocalObjectReference: corev1.LocalObjectReference{
-												Name: "poc-backend-cluster-app",
-											},
-											Key: "host",
-										},
-									},
-								},
-							},
-							ImagePullPolicy: corev1.PullNever,
-						},
-					},
-					RestartPolicy: corev1.RestartPolicyOnFailure,
-				},
-			},
+			Template:     template,
 		},
 	}
 
-	jobClient := clientset.BatchV1().Jobs("app")
-	_, err = jobClient.Create(context.Background(), job, metav1.CreateOptions{})
-	if err != nil {
+	if err = recreateJob(clientset, job); err != nil {
 		err = fmt.Errorf("error creating create-admin job: %w", err)
 		return err
 	}
@@ -112,3 +76,52 @@ func CreateDjangoAdminUser() error {
 	Info("Admin user creation job started")
 	return nil
 }
+
+// adminSecret creates or updates the admin credentials secret from frontend.admin.
+// If no password is set, the existing password is kept or a new one is generated and returned.
+func adminSecret(clientset *kubernetes.Clientset) (generated string, err error) {
+	secrets := clientset.CoreV1().Secrets("app")
+
+	password := viper.GetString("frontend.admin.password")
+	if password == "" {
+		existing, err := secrets.Get(context.Background(), AdminSecretName, metav1.GetOptions{})
+		if err == nil && len(existing.Data["password"]) > 0 {
+			password = string(existing.Data["password"])
+		} else if err != nil && !errors.IsNotFound(err) {
+			return "", err
+		} else {
+			if generated, err = generateRandomString(24); err != nil {
+				err = fmt.Errorf("error generating admin password: %w", err)
+				return "", err
+			}
+			password = generated
+		}
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      AdminSecretName,
+			Namespace: "app",
+			Labels: map[string]string{
+				"app.kubernetes.io/component": "secret",
+				"app.kubernetes.io/name":      "create-admin",
+			},
+		},
+		StringData: map[string]string{
+			"username": viper.GetString("frontend.admin.username"),
+			"email":    viper.GetString("frontend.admin.email"),
+			"password": password,
+		},
+		Type: corev1.SecretTypeOpaque,
+	}
+
+	_, err = secrets.Create(context.Background(), secret, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		_, err = secrets.Update(context.Background(), secret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return generated, nil
+}
@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/viper"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/harvey-earth/pocdeploy/internal/models"
+)
+
+// WorkerPrefix is prepended to worker names for their deployments, so they can't collide with the frontend
+const WorkerPrefix = "worker-"
+
+// loadWorkers reads frontend.workers, setting defaults and validating each entry
+func loadWorkers() ([]models.Worker, error) {
+	var workers []models.Worker
+	if err := viper.UnmarshalKey("frontend.workers", &workers); err != nil {
+		err = fmt.Errorf("error reading frontend.workers: %w", err)
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for i := range workers {
+		w := &workers[i]
+		if errs := validation.IsDNS1123Label(WorkerPrefix + w.Name); w.Name == "" || len(errs) > 0 {
+			return nil, fmt.Errorf("frontend.workers name %q must be a lowercase DNS label", w.Name)
+		}
+		if names[w.Name] {
+			return nil, fmt.Errorf("frontend.workers name %q is used more than once", w.Name)
+		}
+		names[w.Name] = true
+
+		if len(w.Command) == 0 {
+			return nil, fmt.Errorf("frontend.workers %s is missing command", w.Name)
+		}
+		if w.Replicas == 0 {
+			w.Replicas = 1
+		}
+		if w.MetricsPort < 0 || w.MetricsPort > 65535 {
+			return nil, fmt.Errorf("frontend.workers %s metrics_port %d is not a valid port", w.Name, w.MetricsPort)
+		}
+		for name, value := range w.Resources.Requests {
+			if _, err := resource.ParseQuantity(value); err != nil {
+				return nil, fmt.Errorf("frontend.workers %s resources.requests.%s %q is not a valid quantity: %w", w.Name, name, value, err)
+			}
+		}
+		for name, value := range w.Resources.Limits {
+			if _, err := resource.ParseQuantity(value); err != nil {
+				return nil, fmt.Errorf("frontend.workers %s resources.limits.%s %q is not a valid quantity: %w", w.Name, name, value, err)
+			}
+		}
+	}
+
+	return workers, nil
+}
+
+// CheckWorkersConfig validates frontend.workers so mistakes are caught before anything is created
+func CheckWorkersConfig() error {
+	_, err := loadWorkers()
+	return err
+}
+
+// workerDeployments creates or updates a deployment for each worker, and deletes the deployments of removed workers
+func workerDeployments(clientset *kubernetes.Clientset, fw Framework, cfg appConfig) error {
+	workers, err := loadWorkers()
+	if err != nil {
+		return err
+	}
+	deployments := clientset.AppsV1().Deployments(AppNamespace)
+
+	configured := map[string]bool{}
+	for _, w := range workers {
+		deployment := workerDeployment(fw, cfg, w)
+		configured[deployment.Name] = true
+
+		msg := fmt.Sprintf("Configuring worker %s", deployment.Name)
+		Debug(msg)
+		_, err := deployments.Create(context.Background(), deployment, metav1.CreateOptions{})
+		if errors.IsAlreadyExists(err) {
+			_, err = deployments.Update(context.Background(), deployment, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			err = fmt.Errorf("error creating worker %s: %w", deployment.Name, err)
+			return err
+		}
+	}
+
+	existing, err := deployments.List(context.Background(), metav1.ListOptions{
+		LabelSelector: "app.kubernetes.io/component=worker",
+	})
+	if err != nil {
+		err = fmt.Errorf("error listing workers: %w", err)
+		return err
+	}
+	for _, deployment := range existing.Items {
+		if configured[deployment.Name] {
+			continue
+		}
+		msg := fmt.Sprintf("Deleting worker %s", deployment.Name)
+		Debug(msg)
+		err = deployments.Delete(context.Background(), deployment.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			err = fmt.Errorf("error deleting worker %s: %w", deployment.Name, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// workerDeployment renders the deployment of a worker from the shared pod template. Workers connect through the
+// pooler like the frontend, and have no service or ingress.
+func workerDeployment(fw Framework, cfg appConfig, w models.Worker) *appsv1.Deployment {
+	name := WorkerPrefix + w.Name
+	labels := map[string]string{
+		"app.kubernetes.io/component": "worker",
+		"app.kubernetes.io/name":      name,
+	}
+
+	template := podTemplate(fw, cfg, workload{
+		name:    name,
+		command: w.Command,
+		pooled:  true,
+	})
+	template.Labels["app.kubernetes.io/component"] = "worker"
+	container := &template.Spec.Containers[0]
+	container.Resources = corev1.ResourceRequirements{
+		Requests: resourceList(w.Resources.Requests),
+		Limits:   resourceList(w.Resources.Limits),
+	}
+	if len(w.LivenessCommand) > 0 {
+		container.LivenessProbe = &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				Exec: &corev1.ExecAction{
+					Command: w.LivenessCommand,
+				},
+			},
+			InitialDelaySeconds: 30,
+			PeriodSeconds:       30,
+			TimeoutSeconds:      10,
+		}
+	}
+	if w.MetricsPort > 0 {
+		container.Ports = []corev1.ContainerPort{
+			{
+				Name:          "metrics",
+				ContainerPort: w.MetricsPort,
+				Protocol:      corev1.ProtocolTCP,
+			},
+		}
+	}
+
+	reps := w.Replicas
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: AppNamespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &reps,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app.kubernetes.io/name": name,
+				},
+			},
+			Template: template,
+		},
+	}
+}
+
+// resourceList converts validated quantities from the config to a resource list, nil if there are none
+func resourceList(values map[string]string) corev1.ResourceList {
+	if len(values) == 0 {
+		return nil
+	}
+
+	list := corev1.ResourceList{}
+	for name, value := range values {
+		list[corev1.ResourceName(name)] = resource.MustParse(value)
+	}
+
+	return list
+}
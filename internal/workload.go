@@ -0,0 +1,148 @@
+package internal
+
+import (
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// workload describes a container run from the frontend image
+type workload struct {
+	// name is used for the container and the app.kubernetes.io/name label
+	name string
+	// command overrides the image command if set
+	command []string
+	// readOnly adds DATABASE_READ_HOST with the CloudNative PG read only service
+	readOnly bool
+	// pooled points DATABASE_HOST at the pooler if backend.pooler is enabled. Jobs connect directly as migrations
+	// can rely on session state that transaction pooling doesn't keep.
+	pooled bool
+	// env is added after the shared variables
+	env []corev1.EnvVar
+}
+
+// BuildpacksLauncher sets up the environment of buildpack images, commands that replace the image entrypoint run
+// through it
+const BuildpacksLauncher = "/cnb/lifecycle/launcher"
+
+// podTemplate builds the pod template shared by every frontend workload, setting the database, services, framework
+// and app config variables along with the config files
+func podTemplate(fw Framework, cfg appConfig, w workload) corev1.PodTemplateSpec {
+	imgStr := viper.GetString("frontend.image") + ":" + viper.GetString("frontend.version")
+
+	command := w.command
+	if len(command) > 0 && viper.GetString("build.strategy") == "buildpacks" {
+		command = append([]string{BuildpacksLauncher}, command...)
+	}
+
+	env := databaseEnv(w.readOnly, w.pooled)
+	env = append(env, servicesEnv()...)
+	env = append(env, fw.Env()...)
+	env = append(env, cfg.env...)
+	env = append(env, w.env...)
+
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{
+				"app.kubernetes.io/name": w.name,
+			},
+			Annotations: map[string]string{
+				ConfigHashAnnotation: cfg.hash,
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:            w.name,
+					Image:           imgStr,
+					Command:         command,
+					Env:             env,
+					EnvFrom:         cfg.envFrom,
+					VolumeMounts:    cfg.volumeMounts,
+					ImagePullPolicy: corev1.PullNever,
+				},
+			},
+			Volumes: cfg.volumes,
+		},
+	}
+}
+
+// databaseEnv returns the DATABASE_* variables from the backend app secret, along with DATABASE_ENGINE and a
+// DATABASE_URL composed from them. If pooled is set and the pooler is enabled, DATABASE_HOST is the pooler service.
+// If readOnly is set, DATABASE_READ_HOST is the read only pooler when pooled and enabled, or else the read only
+// service, while DATABASE_HOST stays on the primary for writes.
+func databaseEnv(readOnly, pooled bool) []corev1.EnvVar {
+	cluster := activeClusterName()
+	host := ""
+	if pooled && poolerEnabled() {
+		host = poolerName(cluster, "rw")
+	}
+
+	keys := []struct {
+		name string
+		key  string
+	}{
+		{"DATABASE_NAME", "dbname"},
+		{"DATABASE_USER", "username"},
+		{"DATABASE_PASSWORD", "password"},
+		{"DATABASE_HOST", "host"},
+		{"DATABASE_PORT", "port"},
+	}
+
+	env := []corev1.EnvVar{}
+	for _, k := range keys {
+		if k.name == "DATABASE_HOST" && host != "" {
+			env = append(env, corev1.EnvVar{
+				Name:  k.name,
+				Value: host,
+			})
+			continue
+		}
+		env = append(env, corev1.EnvVar{
+			Name: k.name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: databaseSecretName(),
+					},
+					Key: k.key,
+				},
+			},
+		})
+	}
+
+	if readOnly {
+		readHost := cluster + "-ro"
+		if pooled && readOnlyPoolerEnabled() {
+			readHost = poolerName(cluster, "ro")
+		}
+		env = append(env, corev1.EnvVar{
+			Name:  "DATABASE_READ_HOST",
+			Value: readHost,
+		})
+	}
+
+	env = append(env, corev1.EnvVar{
+		Name:  "DATABASE_ENGINE",
+		Value: databaseScheme(),
+	})
+	url := databaseScheme() + "://$(DATABASE_USER):$(DATABASE_PASSWORD)@$(DATABASE_HOST):$(DATABASE_PORT)/$(DATABASE_NAME)"
+	if sslmode := databaseSSLMode(); sslmode != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "DATABASE_SSLMODE",
+			Value: sslmode,
+		})
+		url += "?sslmode=" + sslmode
+	}
+	env = append(env, corev1.EnvVar{
+		Name:  "DATABASE_URL",
+		Value: url,
+	})
+
+	return env
+}
+
+// databaseSSLMode returns the libpq sslmode from frontend.database.sslmode, or an empty string if unset
+func databaseSSLMode() string {
+	return viper.GetString("frontend.database.sslmode")
+}
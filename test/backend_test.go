@@ -0,0 +1,122 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/harvey-earth/pocdeploy/internal"
+)
+
+// fakeClusterAPI serves the CloudNative PG Cluster with bootstrap, records updates to it, and reports every other
+// object as not found
+func fakeClusterAPI(t *testing.T, bootstrap map[string]any) (*httptest.Server, *map[string]any) {
+	clusterPath := fmt.Sprintf("/apis/postgresql.cnpg.io/v1/namespaces/%s/clusters/%s", internal.AppNamespace, internal.ClusterName)
+	cluster := map[string]any{
+		"apiVersion": "postgresql.cnpg.io/v1",
+		"kind":       "Cluster",
+		"metadata": map[string]any{
+			"name":      internal.ClusterName,
+			"namespace": internal.AppNamespace,
+		},
+		"spec": map[string]any{
+			"instances": 3,
+			"bootstrap": bootstrap,
+			"storage": map[string]any{
+				"size": "1Gi",
+			},
+		},
+	}
+	var updated map[string]any
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == clusterPath && r.Method == http.MethodGet:
+			json.NewEncoder(w).Encode(cluster)
+		case r.URL.Path == clusterPath && r.Method == http.MethodPut:
+			if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+				t.Error(err)
+			}
+			json.NewEncoder(w).Encode(updated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]any{
+				"apiVersion": "v1",
+				"kind":       "Status",
+				"status":     "Failure",
+				"reason":     "NotFound",
+				"code":       http.StatusNotFound,
+			})
+		}
+	}))
+
+	return server, &updated
+}
+
+// useFakeCluster points the kubeconfig read by pocdeploy at server
+func useFakeCluster(t *testing.T, server *httptest.Server) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: fake
+  cluster:
+    server: %s
+contexts:
+- name: fake
+  context:
+    cluster: fake
+current-context: fake
+`, server.URL)
+	assert.NoError(t, os.MkdirAll(filepath.Join(home, ".kube"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(home, ".kube", "config"), []byte(kubeconfig), 0o600))
+}
+
+func TestUpdateBackendRecoveryBootstrap(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	assert.NoError(t, internal.InitLogger())
+
+	server, updated := fakeClusterAPI(t, map[string]any{
+		"recovery": map[string]any{
+			"database": "app",
+			"owner":    "app",
+			"source":   "origin",
+		},
+	})
+	defer server.Close()
+	useFakeCluster(t, server)
+
+	assert.NoError(t, internal.UpdateBackend())
+	assert.NotNil(t, *updated)
+}
+
+func TestUpdateBackendRejectsDatabaseChange(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	assert.NoError(t, internal.InitLogger())
+	viper.Set("backend.database", "other")
+
+	for _, bootstrap := range []string{"initdb", "recovery"} {
+		server, updated := fakeClusterAPI(t, map[string]any{
+			bootstrap: map[string]any{
+				"database": "app",
+				"owner":    "app",
+			},
+		})
+		useFakeCluster(t, server)
+
+		assert.ErrorContains(t, internal.UpdateBackend(), "bootstrap."+bootstrap+".database can't be changed")
+		assert.Nil(t, *updated)
+		server.Close()
+	}
+}
@@ -0,0 +1,90 @@
+package test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/harvey-earth/pocdeploy/internal"
+)
+
+const secretReferencesConfig = `
+backend:
+  instances: 2
+  backup:
+    destination: 's3://backups/'
+    schedule: '0 0 0 * * *'
+    secret_access_key: '${env:POCDEPLOY_TEST_SECRET}'
+frontend:
+  env:
+    literal: 'kept'
+    token: '${env:POCDEPLOY_TEST_SECRET}'
+  workers:
+    - name: 'worker'
+      command: ['run', '${env:POCDEPLOY_TEST_SECRET}']
+`
+
+func TestResolveSecretReferencesKeepsSiblings(t *testing.T) {
+	t.Setenv("POCDEPLOY_TEST_SECRET", "s3cret")
+	viper.Reset()
+	defer viper.Reset()
+	viper.SetConfigType("yaml")
+	assert.NoError(t, viper.ReadConfig(bytes.NewBufferString(secretReferencesConfig)))
+
+	assert.NoError(t, internal.ResolveSecretReferences())
+
+	assert.Equal(t, 2, viper.GetInt("backend.instances"))
+	assert.Equal(t, "s3://backups/", viper.GetString("backend.backup.destination"))
+	assert.Equal(t, "0 0 0 * * *", viper.GetString("backend.backup.schedule"))
+	assert.Equal(t, "s3cret", viper.GetString("backend.backup.secret_access_key"))
+
+	var backend struct {
+		Instances int `mapstructure:"instances"`
+		Backup    struct {
+			Destination string `mapstructure:"destination"`
+		} `mapstructure:"backup"`
+	}
+	assert.NoError(t, viper.UnmarshalKey("backend", &backend))
+	assert.Equal(t, 2, backend.Instances)
+	assert.Equal(t, "s3://backups/", backend.Backup.Destination)
+
+	assert.Equal(t, map[string]string{"literal": "kept", "token": "s3cret"}, viper.GetStringMapString("frontend.env"))
+
+	var workers []struct {
+		Command []string `mapstructure:"command"`
+	}
+	assert.NoError(t, viper.UnmarshalKey("frontend.workers", &workers))
+	assert.Equal(t, []string{"run", "s3cret"}, workers[0].Command)
+}
+
+func TestResolveSecretReferencesMissingEnv(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("backend.backup.secret_access_key", "${env:POCDEPLOY_TEST_UNSET}")
+
+	assert.Error(t, internal.ResolveSecretReferences())
+}
+
+func TestBindDeprecatedEnv(t *testing.T) {
+	t.Setenv("NAME", "old")
+	t.Setenv("FRONTEND_TYPE", "rails")
+	t.Setenv("POCDEPLOY_FRONTEND_TYPE", "django")
+	viper.Reset()
+	defer viper.Reset()
+	viper.SetEnvPrefix("pocdeploy")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+	viper.SetConfigType("yaml")
+	assert.NoError(t, viper.ReadConfig(bytes.NewBufferString("name: poc\nfrontend:\n  type: golang\n  image: counter\n")))
+
+	deprecated, err := internal.BindDeprecatedEnv()
+	assert.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"NAME", "FRONTEND_TYPE"}, deprecated)
+	assert.Equal(t, "old", viper.GetString("name"))
+	assert.Equal(t, "django", viper.GetString("frontend.type"))
+	assert.Equal(t, "counter", viper.GetString("frontend.image"))
+}
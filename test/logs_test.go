@@ -0,0 +1,136 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/harvey-earth/pocdeploy/internal"
+)
+
+// fakeLogsAPI lists pod-b with a main container and pod-a with an init container and a main container in every
+// namespace, serves "<container> one\n<container> two" as their logs, and records the namespace and label selector
+// of each pod list
+func fakeLogsAPI(listed *[]string) *httptest.Server {
+	running := map[string]any{"running": map[string]any{}}
+	completed := map[string]any{"terminated": map[string]any{"exitCode": 0}}
+	pod := func(namespace, name string, init bool) map[string]any {
+		status := map[string]any{
+			"containerStatuses": []any{
+				map[string]any{"name": "main", "state": running},
+			},
+		}
+		if init {
+			status["initContainerStatuses"] = []any{
+				map[string]any{"name": "init", "state": completed},
+			}
+		}
+		return map[string]any{
+			"metadata": map[string]any{"name": name, "namespace": namespace},
+			"status":   status,
+		}
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		switch {
+		case len(parts) == 5 && parts[4] == "pods":
+			*listed = append(*listed, parts[3]+" "+r.URL.Query().Get("labelSelector"))
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]any{
+				"apiVersion": "v1",
+				"kind":       "PodList",
+				"items":      []any{pod(parts[3], "pod-b", false), pod(parts[3], "pod-a", true)},
+			})
+		case len(parts) == 7 && parts[6] == "log":
+			container := r.URL.Query().Get("container")
+			fmt.Fprintf(w, "%s one\n%s two", container, container)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]any{
+				"apiVersion": "v1",
+				"kind":       "Status",
+				"status":     "Failure",
+				"reason":     "NotFound",
+				"code":       http.StatusNotFound,
+			})
+		}
+	}))
+}
+
+func TestLogsComponents(t *testing.T) {
+	cases := []struct {
+		component string
+		backend   string
+		listed    string
+		err       string
+	}{
+		{component: "frontend", listed: "app app.kubernetes.io/name=counter"},
+		{component: "backend", listed: "app cnpg.io/cluster=" + internal.ClusterName},
+		{component: "backend", backend: "mysql", listed: "app mysql.oracle.com/cluster=" + internal.ClusterName},
+		{component: "init", listed: "app app.kubernetes.io/name=backend-init"},
+		{component: "admin", listed: "app app.kubernetes.io/name=create-admin"},
+		{component: "cnpg-operator", listed: "cnpg-system app.kubernetes.io/name=cloudnative-pg"},
+		{component: "cnpg-operator", backend: "mysql", err: "cnpg-operator logs is not supported for the mysql backend"},
+		{component: "prometheus", listed: "app prometheus=monitoring"},
+		{component: "redis", err: `unknown component "redis"`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.component+c.backend, func(t *testing.T) {
+			viper.Reset()
+			defer viper.Reset()
+			assert.NoError(t, internal.InitLogger())
+			viper.Set("frontend.image", "counter")
+			viper.Set("backend.type", c.backend)
+
+			var listed []string
+			server := fakeLogsAPI(&listed)
+			defer server.Close()
+			useFakeCluster(t, server)
+
+			var out bytes.Buffer
+			err := internal.Logs([]string{c.component}, false, 0, &out)
+			if c.err != "" {
+				assert.ErrorContains(t, err, c.err)
+				assert.Empty(t, listed)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, []string{c.listed}, listed)
+		})
+	}
+}
+
+func TestLogsPrefixesLines(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	assert.NoError(t, internal.InitLogger())
+
+	var listed []string
+	server := fakeLogsAPI(&listed)
+	defer server.Close()
+	useFakeCluster(t, server)
+
+	var out bytes.Buffer
+	assert.NoError(t, internal.Logs([]string{"init"}, false, 0, &out))
+
+	// Pods are sorted by name and init containers come first, each container is streamed in full before the next
+	assert.Equal(t, strings.Join([]string{
+		"[pod-a/init] init one",
+		"[pod-a/init] init two",
+		"[pod-a/main] main one",
+		"[pod-a/main] main two",
+		"[pod-b/main] main one",
+		"[pod-b/main] main two",
+		"",
+	}, "\n"), out.String())
+}